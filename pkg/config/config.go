@@ -17,20 +17,73 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/grafana/regexp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"gopkg.in/yaml.v2"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
+var (
+	configLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "yace_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configLastReloadSuccessTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "yace_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+)
+
 type ScrapeConf struct {
-	APIVersion      string             `yaml:"apiVersion"`
-	StsRegion       string             `yaml:"sts-region"`
-	Discovery       Discovery          `yaml:"discovery"`
-	Static          []*Static          `yaml:"static"`
-	CustomNamespace []*CustomNamespace `yaml:"customNamespace"`
+	APIVersion        string             `yaml:"apiVersion"`
+	StsRegion         string             `yaml:"sts-region"`
+	Discovery         Discovery          `yaml:"discovery"`
+	Static            []*Static          `yaml:"static"`
+	CustomNamespace   []*CustomNamespace `yaml:"customNamespace"`
+	DecoupledScraping DecoupledScraping  `yaml:"decoupledScraping"`
+	AWSSdkVersion     string             `yaml:"awsSdkVersion"`
+	Includes          []string           `yaml:"includes"`
+	// CounterNamespaces lists the namespaces whose `Sum` statistic is monotonic (e.g.
+	// AWS/ApplicationELB RequestCount) and should therefore be tracked with a stable start time
+	// across scrapes instead of being reported as a plain gauge; see
+	// promutil/starttime.NewNamespaceClassifier, which model.JobsConfig.CounterNamespaces feeds.
+	CounterNamespaces []string `yaml:"counterNamespaces"`
+
+	// current holds the last successfully validated model.JobsConfig behind an atomic
+	// pointer so that Reload can swap it in without the running exporter ever observing a
+	// partially-updated configuration mid-scrape.
+	current atomic.Pointer[model.JobsConfig]
+}
+
+// Supported values for AWSSdkVersion / Job.AWSSdkVersion / Static.AWSSdkVersion /
+// CustomNamespace.AWSSdkVersion. The client factory that picks an AWS SDK v1 or v2
+// CloudWatch/tagging client based on model.DiscoveryJob/StaticJob/CustomNamespaceJob's resolved
+// AWSSdkVersion lives in pkg/clients/cloudwatch, which (like the rest of the AWS API call layer)
+// isn't part of this snapshot; this package only validates and resolves the setting.
+const (
+	AWSSdkVersionV1 = "v1"
+	AWSSdkVersionV2 = "v2"
+)
+
+// DecoupledScraping configures a background goroutine, running on its own cadence, that
+// refreshes an in-memory cache of the last-successful collection result. When enabled, the
+// `/metrics` handler serves this cache instead of blocking on a live CloudWatch collection,
+// decoupling Prometheus scrape latency from AWS API latency. pkg/scheduler.Cache implements
+// the goroutine and cache described here; this snapshot has no main/server entrypoint to start
+// it from, so wiring it into an actual `/metrics` handler is left to that entrypoint.
+type DecoupledScraping struct {
+	Enabled        bool          `yaml:"enabled"`
+	ScrapeInterval time.Duration `yaml:"scrapeInterval"`
+	Jitter         time.Duration `yaml:"jitter"`
 }
 
 type Discovery struct {
@@ -52,44 +105,64 @@ type JobLevelMetricFields struct {
 	Delay                  int64    `yaml:"delay"`
 	NilToZero              *bool    `yaml:"nilToZero"`
 	AddCloudwatchTimestamp *bool    `yaml:"addCloudwatchTimestamp"`
+	// RateOverPreviousPeriod replaces this metric's value with a per-second rate computed from
+	// the current and previous scrape's datapoint; no separate `_rate` series is exposed.
+	RateOverPreviousPeriod *bool `yaml:"rate"`
 }
 
 type Job struct {
-	Regions                     []string  `yaml:"regions"`
-	Type                        string    `yaml:"type"`
-	Roles                       []Role    `yaml:"roles"`
-	SearchTags                  []Tag     `yaml:"searchTags"`
-	CustomTags                  []Tag     `yaml:"customTags"`
-	DimensionNameRequirements   []string  `yaml:"dimensionNameRequirements"`
-	Metrics                     []*Metric `yaml:"metrics"`
-	RoundingPeriod              *int64    `yaml:"roundingPeriod"`
-	RecentlyActiveOnly          bool      `yaml:"recentlyActiveOnly"`
-	IncludeContextOnInfoMetrics bool      `yaml:"includeContextOnInfoMetrics"`
-	IncludeLinkedAccounts       []string  `yaml:"includeLinkedAccounts"`
+	Regions                     []string      `yaml:"regions"`
+	Type                        string        `yaml:"type"`
+	Roles                       []Role        `yaml:"roles"`
+	SearchTags                  []Tag         `yaml:"searchTags"`
+	CustomTags                  []Tag         `yaml:"customTags"`
+	DimensionNameRequirements   []string      `yaml:"dimensionNameRequirements"`
+	Metrics                     []*Metric     `yaml:"metrics"`
+	RoundingPeriod              *int64        `yaml:"roundingPeriod"`
+	RecentlyActiveOnly          bool          `yaml:"recentlyActiveOnly"`
+	IncludeContextOnInfoMetrics bool          `yaml:"includeContextOnInfoMetrics"`
+	IncludeLinkedAccounts       []string      `yaml:"includeLinkedAccounts"`
+	ScrapeInterval              time.Duration `yaml:"scrapeInterval"`
+	AWSSdkVersion               string        `yaml:"awsSdkVersion"`
+	TopK                        *TopK         `yaml:"topK"`
 	JobLevelMetricFields        `yaml:",inline"`
 }
 
+// TopK configures the optional top-K post-processing stage (promutil.ApplyTopK) applied to a
+// discovery job's output, for namespaces whose resource count can explode series cardinality
+// (S3 buckets, Lambda functions, ...).
+type TopK struct {
+	Limit            int      `yaml:"limit"`
+	Order            string   `yaml:"order"`
+	GroupBy          []string `yaml:"groupBy"`
+	OtherAggregation string   `yaml:"otherAggregation"`
+}
+
 type Static struct {
-	Name       string      `yaml:"name"`
-	Regions    []string    `yaml:"regions"`
-	Roles      []Role      `yaml:"roles"`
-	Namespace  string      `yaml:"namespace"`
-	CustomTags []Tag       `yaml:"customTags"`
-	Dimensions []Dimension `yaml:"dimensions"`
-	Metrics    []*Metric   `yaml:"metrics"`
+	Name           string        `yaml:"name"`
+	Regions        []string      `yaml:"regions"`
+	Roles          []Role        `yaml:"roles"`
+	Namespace      string        `yaml:"namespace"`
+	CustomTags     []Tag         `yaml:"customTags"`
+	Dimensions     []Dimension   `yaml:"dimensions"`
+	Metrics        []*Metric     `yaml:"metrics"`
+	ScrapeInterval time.Duration `yaml:"scrapeInterval"`
+	AWSSdkVersion  string        `yaml:"awsSdkVersion"`
 }
 
 type CustomNamespace struct {
-	Regions                   []string  `yaml:"regions"`
-	Name                      string    `yaml:"name"`
-	Namespace                 string    `yaml:"namespace"`
-	RecentlyActiveOnly        bool      `yaml:"recentlyActiveOnly"`
-	Roles                     []Role    `yaml:"roles"`
-	Metrics                   []*Metric `yaml:"metrics"`
-	CustomTags                []Tag     `yaml:"customTags"`
-	DimensionNameRequirements []string  `yaml:"dimensionNameRequirements"`
-	RoundingPeriod            *int64    `yaml:"roundingPeriod"`
-	IncludeLinkedAccounts     []string  `yaml:"includeLinkedAccounts"`
+	Regions                   []string      `yaml:"regions"`
+	Name                      string        `yaml:"name"`
+	Namespace                 string        `yaml:"namespace"`
+	RecentlyActiveOnly        bool          `yaml:"recentlyActiveOnly"`
+	Roles                     []Role        `yaml:"roles"`
+	Metrics                   []*Metric     `yaml:"metrics"`
+	CustomTags                []Tag         `yaml:"customTags"`
+	DimensionNameRequirements []string      `yaml:"dimensionNameRequirements"`
+	RoundingPeriod            *int64        `yaml:"roundingPeriod"`
+	IncludeLinkedAccounts     []string      `yaml:"includeLinkedAccounts"`
+	ScrapeInterval            time.Duration `yaml:"scrapeInterval"`
+	AWSSdkVersion             string        `yaml:"awsSdkVersion"`
 	JobLevelMetricFields      `yaml:",inline"`
 }
 
@@ -101,6 +174,7 @@ type Metric struct {
 	Delay                  int64    `yaml:"delay"`
 	NilToZero              *bool    `yaml:"nilToZero"`
 	AddCloudwatchTimestamp *bool    `yaml:"addCloudwatchTimestamp"`
+	RateOverPreviousPeriod *bool    `yaml:"rate"`
 }
 
 type Dimension struct {
@@ -109,8 +183,18 @@ type Dimension struct {
 }
 
 type Role struct {
-	RoleArn    string `yaml:"roleArn"`
-	ExternalID string `yaml:"externalId"`
+	RoleArn              string            `yaml:"roleArn"`
+	ExternalID           string            `yaml:"externalId"`
+	SessionName          string            `yaml:"sessionName"`
+	DurationSeconds      int               `yaml:"durationSeconds"`
+	SessionTags          map[string]string `yaml:"sessionTags"`
+	TransitiveTagKeys    []string          `yaml:"transitiveTagKeys"`
+	SourceIdentity       string            `yaml:"sourceIdentity"`
+	WebIdentityTokenFile string            `yaml:"webIdentityTokenFile"`
+	// ChainedRoles are assumed in order after the current role, each one using the
+	// credentials produced by the role before it (e.g. for landing-zone / control-tower
+	// accounts where a job must hop through an intermediate account role).
+	ChainedRoles []Role `yaml:"chainedRoles"`
 }
 
 func (r *Role) ValidateRole(roleIdx int, parent string) error {
@@ -118,10 +202,100 @@ func (r *Role) ValidateRole(roleIdx int, parent string) error {
 		return fmt.Errorf("Role [%d] in %v: RoleArn should not be empty", roleIdx, parent)
 	}
 
+	if r.WebIdentityTokenFile != "" {
+		if r.ExternalID != "" {
+			return fmt.Errorf("Role [%d] in %v: webIdentityTokenFile cannot be combined with externalId", roleIdx, parent)
+		}
+		if r.RoleArn == "" {
+			return fmt.Errorf("Role [%d] in %v: webIdentityTokenFile requires roleArn to be set", roleIdx, parent)
+		}
+	}
+
+	if len(r.TransitiveTagKeys) > 0 && len(r.SessionTags) == 0 {
+		return fmt.Errorf("Role [%d] in %v: transitiveTagKeys requires sessionTags to be set", roleIdx, parent)
+	}
+
+	for chainIdx, chained := range r.ChainedRoles {
+		if err := chained.ValidateRole(chainIdx, fmt.Sprintf("%v chainedRoles[%d]", parent, roleIdx)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (c *ScrapeConf) Load(file string, logger *slog.Logger) (model.JobsConfig, error) {
+	jobsCfg, err := c.parseAndValidate(file, logger)
+	if err != nil {
+		configLastReloadSuccessful.Set(0)
+		return model.JobsConfig{}, err
+	}
+
+	c.current.Store(&jobsCfg)
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+	return jobsCfg, nil
+}
+
+// Current returns the last successfully validated model.JobsConfig. The running exporter
+// should call this on every scrape instead of holding on to the result of Load, so that it
+// always observes the outcome of the most recent Reload.
+func (c *ScrapeConf) Current() model.JobsConfig {
+	if cfg := c.current.Load(); cfg != nil {
+		return *cfg
+	}
+	return model.JobsConfig{}
+}
+
+// Reload re-reads and re-validates file, atomically swapping it in as the config returned by
+// Current only if validation succeeds; on failure the previously loaded config is left in
+// place, the failure is logged, and the error is also returned so the caller (WatchForReloadSignal,
+// or a `/-/reload` HTTP handler built the same way) can report it to whoever triggered the reload.
+func (c *ScrapeConf) Reload(file string, logger *slog.Logger) error {
+	fresh := &ScrapeConf{}
+	jobsCfg, err := fresh.parseAndValidate(file, logger)
+	if err != nil {
+		logger.Error("config reload failed, keeping the previously loaded configuration", "file", file, "err", err)
+		configLastReloadSuccessful.Set(0)
+		return err
+	}
+
+	c.current.Store(&jobsCfg)
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// WatchForReloadSignal installs a SIGHUP handler that calls Reload(file, logger) every time the
+// process receives it, logging (but not otherwise acting on) any error Reload returns. It
+// returns a stop function that removes the handler; callers should defer it on shutdown. A
+// `POST /-/reload` HTTP handler can reuse the same pattern by calling c.Reload directly instead
+// of waiting on a signal.
+func (c *ScrapeConf) WatchForReloadSignal(file string, logger *slog.Logger) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				if err := c.Reload(file, logger); err != nil {
+					logger.Error("config reload triggered by SIGHUP failed", "err", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+func (c *ScrapeConf) parseAndValidate(file string, logger *slog.Logger) (model.JobsConfig, error) {
 	yamlFile, err := os.ReadFile(file)
 	if err != nil {
 		return model.JobsConfig{}, err
@@ -133,6 +307,10 @@ func (c *ScrapeConf) Load(file string, logger *slog.Logger) (model.JobsConfig, e
 
 	logConfigErrors(yamlFile, logger)
 
+	if err := c.loadIncludes(file); err != nil {
+		return model.JobsConfig{}, err
+	}
+
 	for _, job := range c.Discovery.Jobs {
 		if len(job.Roles) == 0 {
 			job.Roles = []Role{{}} // use current IAM role
@@ -154,6 +332,76 @@ func (c *ScrapeConf) Load(file string, logger *slog.Logger) (model.JobsConfig, e
 	return c.Validate(logger)
 }
 
+// includeFragment is the subset of ScrapeConf that an included file is allowed to contribute;
+// apiVersion, includes, decoupledScraping, etc. only make sense in the main config file.
+type includeFragment struct {
+	Discovery       Discovery          `yaml:"discovery"`
+	Static          []*Static          `yaml:"static"`
+	CustomNamespace []*CustomNamespace `yaml:"customNamespace"`
+}
+
+// loadIncludes resolves c.Includes as glob patterns relative to the directory of the main
+// config file, merging each matched fragment's discovery.jobs, static and customNamespace
+// lists into c. It fails if a Static or CustomNamespace name collides with one already loaded,
+// naming both source files in the error so the conflict is easy to locate.
+func (c *ScrapeConf) loadIncludes(mainFile string) error {
+	if len(c.Includes) == 0 {
+		return nil
+	}
+
+	staticSources := sourceFileIndex(c.Static, func(s *Static) string { return s.Name }, mainFile)
+	customNamespaceSources := sourceFileIndex(c.CustomNamespace, func(j *CustomNamespace) string { return j.Name }, mainFile)
+
+	baseDir := filepath.Dir(mainFile)
+	for _, pattern := range c.Includes {
+		matches, err := filepath.Glob(filepath.Join(baseDir, pattern))
+		if err != nil {
+			return fmt.Errorf("includes: invalid glob pattern %q: %w", pattern, err)
+		}
+
+		for _, includedFile := range matches {
+			yamlFile, err := os.ReadFile(includedFile)
+			if err != nil {
+				return fmt.Errorf("includes: failed to read %q: %w", includedFile, err)
+			}
+
+			var fragment includeFragment
+			if err := yaml.Unmarshal(yamlFile, &fragment); err != nil {
+				return fmt.Errorf("includes: failed to parse %q: %w", includedFile, err)
+			}
+
+			for _, s := range fragment.Static {
+				if existing, ok := staticSources[s.Name]; ok {
+					return fmt.Errorf("includes: duplicate static job name %q found in %q and %q", s.Name, existing, includedFile)
+				}
+				staticSources[s.Name] = includedFile
+			}
+			for _, j := range fragment.CustomNamespace {
+				if existing, ok := customNamespaceSources[j.Name]; ok {
+					return fmt.Errorf("includes: duplicate customNamespace job name %q found in %q and %q", j.Name, existing, includedFile)
+				}
+				customNamespaceSources[j.Name] = includedFile
+			}
+
+			c.Discovery.Jobs = append(c.Discovery.Jobs, fragment.Discovery.Jobs...)
+			c.Static = append(c.Static, fragment.Static...)
+			c.CustomNamespace = append(c.CustomNamespace, fragment.CustomNamespace...)
+		}
+	}
+
+	return nil
+}
+
+// sourceFileIndex builds a name -> source file map for a slice of named jobs already present
+// in the main config, used as the starting point for cross-file duplicate detection.
+func sourceFileIndex[T any](items []T, name func(T) string, file string) map[string]string {
+	index := make(map[string]string, len(items))
+	for _, item := range items {
+		index[name(item)] = file
+	}
+	return index
+}
+
 func (c *ScrapeConf) Validate(logger *slog.Logger) (model.JobsConfig, error) {
 	if c.Discovery.Jobs == nil && c.Static == nil && c.CustomNamespace == nil {
 		return model.JobsConfig{}, fmt.Errorf("at least 1 Discovery job, 1 Static or one CustomNamespace must be defined")
@@ -211,9 +459,135 @@ func (c *ScrapeConf) Validate(logger *slog.Logger) (model.JobsConfig, error) {
 		return model.JobsConfig{}, fmt.Errorf("unknown apiVersion value '%s'", c.APIVersion)
 	}
 
+	if err := c.validateDecoupledScraping(); err != nil {
+		return model.JobsConfig{}, err
+	}
+
+	if err := c.validateAWSSdkVersions(logger); err != nil {
+		return model.JobsConfig{}, err
+	}
+
 	return c.toModelConfig(), nil
 }
 
+// validateDecoupledScraping ensures that the scrape interval applying to each job (its own
+// override, or else the global one) is not tighter than the smallest metric period configured
+// for that job; a background scrape running faster than CloudWatch can produce fresh datapoints
+// would just serve stale data.
+func (c *ScrapeConf) validateDecoupledScraping() error {
+	checkInterval := func(parent string, interval time.Duration, metrics []*Metric) error {
+		if interval <= 0 {
+			return nil
+		}
+		var smallest time.Duration
+		for _, m := range metrics {
+			period := time.Duration(m.Period) * time.Second
+			if smallest == 0 || period < smallest {
+				smallest = period
+			}
+		}
+		if smallest > 0 && interval < smallest {
+			return fmt.Errorf("%s: decoupled scrapeInterval (%s) should not be smaller than the smallest metric period (%s)", parent, interval, smallest)
+		}
+		return nil
+	}
+
+	if c.DecoupledScraping.Enabled {
+		for idx, job := range c.Discovery.Jobs {
+			interval := c.DecoupledScraping.ScrapeInterval
+			if job.ScrapeInterval > 0 {
+				interval = job.ScrapeInterval
+			}
+			if err := checkInterval(fmt.Sprintf("Discovery job [%s/%d]", job.Type, idx), interval, job.Metrics); err != nil {
+				return err
+			}
+		}
+		for idx, job := range c.Static {
+			interval := c.DecoupledScraping.ScrapeInterval
+			if job.ScrapeInterval > 0 {
+				interval = job.ScrapeInterval
+			}
+			if err := checkInterval(fmt.Sprintf("Static job [%s/%d]", job.Name, idx), interval, job.Metrics); err != nil {
+				return err
+			}
+		}
+		for idx, job := range c.CustomNamespace {
+			interval := c.DecoupledScraping.ScrapeInterval
+			if job.ScrapeInterval > 0 {
+				interval = job.ScrapeInterval
+			}
+			if err := checkInterval(fmt.Sprintf("CustomNamespace job [%s/%d]", job.Name, idx), interval, job.Metrics); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAWSSdkVersions checks that the global and any per-job `awsSdkVersion` override is
+// one of the supported values and warns when a job is left on v1, since v2 has shown
+// substantially better throughput and memory behavior for GetMetricData batching.
+func (c *ScrapeConf) validateAWSSdkVersions(logger *slog.Logger) error {
+	check := func(parent, version string) error {
+		if version == "" {
+			return nil
+		}
+		if version != AWSSdkVersionV1 && version != AWSSdkVersionV2 {
+			return fmt.Errorf("%s: unknown awsSdkVersion value %q, must be %q or %q", parent, version, AWSSdkVersionV1, AWSSdkVersionV2)
+		}
+		return nil
+	}
+
+	// warnIfV1 logs the same "consider migrating" warning every job-level check below falls
+	// back to, so the three loops only differ in what they iterate over.
+	warnIfV1 := func(parent, jobLevel string) {
+		if resolveAWSSdkVersion(c.AWSSdkVersion, jobLevel) == AWSSdkVersionV1 {
+			logger.Warn(fmt.Sprintf("%s: running on awsSdkVersion v1, consider migrating to v2 for better GetMetricData throughput", parent))
+		}
+	}
+
+	if err := check("top level config", c.AWSSdkVersion); err != nil {
+		return err
+	}
+
+	for idx, job := range c.Discovery.Jobs {
+		parent := fmt.Sprintf("Discovery job [%s/%d]", job.Type, idx)
+		if err := check(parent, job.AWSSdkVersion); err != nil {
+			return err
+		}
+		warnIfV1(parent, job.AWSSdkVersion)
+	}
+	for idx, job := range c.Static {
+		parent := fmt.Sprintf("Static job [%s/%d]", job.Name, idx)
+		if err := check(parent, job.AWSSdkVersion); err != nil {
+			return err
+		}
+		warnIfV1(parent, job.AWSSdkVersion)
+	}
+	for idx, job := range c.CustomNamespace {
+		parent := fmt.Sprintf("CustomNamespace job [%s/%d]", job.Name, idx)
+		if err := check(parent, job.AWSSdkVersion); err != nil {
+			return err
+		}
+		warnIfV1(parent, job.AWSSdkVersion)
+	}
+
+	return nil
+}
+
+// resolveAWSSdkVersion applies job-level-overrides-global precedence, defaulting to v1 for
+// backward compatibility when neither is set.
+func resolveAWSSdkVersion(global, jobLevel string) string {
+	if jobLevel != "" {
+		return jobLevel
+	}
+	if global != "" {
+		return global
+	}
+	return AWSSdkVersionV1
+}
+
 func (j *Job) validateDiscoveryJob(logger *slog.Logger, jobIdx int) error {
 	if j.Type != "" {
 		if svc := SupportedServices.GetService(j.Type); svc == nil {
@@ -389,6 +763,15 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 		}
 	}
 
+	mRateOverPreviousPeriod := m.RateOverPreviousPeriod
+	if mRateOverPreviousPeriod == nil {
+		if discovery != nil && discovery.RateOverPreviousPeriod != nil {
+			mRateOverPreviousPeriod = discovery.RateOverPreviousPeriod
+		} else {
+			mRateOverPreviousPeriod = aws.Bool(false)
+		}
+	}
+
 	if mLength < mPeriod {
 		return fmt.Errorf(
 			"Metric [%s/%d] in %v: length(%d) is smaller than period(%d). This can cause that the data requested is not ready and generate data gaps",
@@ -400,6 +783,7 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 	m.Delay = mDelay
 	m.NilToZero = mNilToZero
 	m.AddCloudwatchTimestamp = mAddCloudwatchTimestamp
+	m.RateOverPreviousPeriod = mRateOverPreviousPeriod
 	m.Statistics = mStatistics
 
 	return nil
@@ -408,6 +792,12 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 	jobsCfg := model.JobsConfig{}
 	jobsCfg.StsRegion = c.StsRegion
+	jobsCfg.DecoupledScraping = model.DecoupledScraping{
+		Enabled:        c.DecoupledScraping.Enabled,
+		ScrapeInterval: c.DecoupledScraping.ScrapeInterval,
+		Jitter:         c.DecoupledScraping.Jitter,
+	}
+	jobsCfg.CounterNamespaces = c.CounterNamespaces
 
 	for _, discoveryJob := range c.Discovery.Jobs {
 		svc := SupportedServices.GetService(discoveryJob.Type)
@@ -425,6 +815,9 @@ func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 		job.IncludeContextOnInfoMetrics = discoveryJob.IncludeContextOnInfoMetrics
 		job.IncludeLinkedAccounts = discoveryJob.IncludeLinkedAccounts
 		job.DimensionsRegexps = svc.ToModelDimensionsRegexp()
+		job.ScrapeInterval = discoveryJob.ScrapeInterval
+		job.AWSSdkVersion = resolveAWSSdkVersion(c.AWSSdkVersion, discoveryJob.AWSSdkVersion)
+		job.TopK = toModelTopK(discoveryJob.TopK)
 
 		job.ExportedTagsOnMetrics = []string{}
 		if len(c.Discovery.ExportedTagsOnMetrics) > 0 {
@@ -445,6 +838,8 @@ func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 		job.CustomTags = toModelTags(staticJob.CustomTags)
 		job.Dimensions = toModelDimensions(staticJob.Dimensions)
 		job.Metrics = toModelMetricConfig(staticJob.Metrics)
+		job.ScrapeInterval = staticJob.ScrapeInterval
+		job.AWSSdkVersion = resolveAWSSdkVersion(c.AWSSdkVersion, staticJob.AWSSdkVersion)
 		jobsCfg.StaticJobs = append(jobsCfg.StaticJobs, job)
 	}
 
@@ -460,6 +855,8 @@ func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 		job.CustomTags = toModelTags(customNamespaceJob.CustomTags)
 		job.Metrics = toModelMetricConfig(customNamespaceJob.Metrics)
 		job.IncludeLinkedAccounts = customNamespaceJob.IncludeLinkedAccounts
+		job.ScrapeInterval = customNamespaceJob.ScrapeInterval
+		job.AWSSdkVersion = resolveAWSSdkVersion(c.AWSSdkVersion, customNamespaceJob.AWSSdkVersion)
 		jobsCfg.CustomNamespaceJobs = append(jobsCfg.CustomNamespaceJobs, job)
 	}
 
@@ -493,14 +890,25 @@ func toModelSearchTags(tags []Tag) []model.SearchTag {
 func toModelRoles(roles []Role) []model.Role {
 	ret := make([]model.Role, 0, len(roles))
 	for _, r := range roles {
-		ret = append(ret, model.Role{
-			RoleArn:    r.RoleArn,
-			ExternalID: r.ExternalID,
-		})
+		ret = append(ret, toModelRole(r))
 	}
 	return ret
 }
 
+func toModelRole(r Role) model.Role {
+	return model.Role{
+		RoleArn:              r.RoleArn,
+		ExternalID:           r.ExternalID,
+		SessionName:          r.SessionName,
+		DurationSeconds:      r.DurationSeconds,
+		SessionTags:          r.SessionTags,
+		TransitiveTagKeys:    r.TransitiveTagKeys,
+		SourceIdentity:       r.SourceIdentity,
+		WebIdentityTokenFile: r.WebIdentityTokenFile,
+		ChainedRoles:         toModelRoles(r.ChainedRoles),
+	}
+}
+
 func toModelDimensions(dimensions []Dimension) []model.Dimension {
 	ret := make([]model.Dimension, 0, len(dimensions))
 	for _, d := range dimensions {
@@ -512,6 +920,21 @@ func toModelDimensions(dimensions []Dimension) []model.Dimension {
 	return ret
 }
 
+// toModelTopK converts a job's optional topK config block into model.TopK, mirroring
+// promutil.TopKConfig's fields. A nil TopK leaves the resulting model.TopK at its zero value,
+// which promutil.ApplyTopK treats as "disabled" (Limit <= 0).
+func toModelTopK(t *TopK) model.TopK {
+	if t == nil {
+		return model.TopK{}
+	}
+	return model.TopK{
+		Limit:            t.Limit,
+		Order:            t.Order,
+		GroupBy:          t.GroupBy,
+		OtherAggregation: t.OtherAggregation,
+	}
+}
+
 func toModelMetricConfig(metrics []*Metric) []*model.MetricConfig {
 	ret := make([]*model.MetricConfig, 0, len(metrics))
 	for _, m := range metrics {
@@ -523,6 +946,7 @@ func toModelMetricConfig(metrics []*Metric) []*model.MetricConfig {
 			Delay:                  m.Delay,
 			NilToZero:              aws.BoolValue(m.NilToZero),
 			AddCloudwatchTimestamp: aws.BoolValue(m.AddCloudwatchTimestamp),
+			RateOverPreviousPeriod: aws.BoolValue(m.RateOverPreviousPeriod),
 		})
 	}
 	return ret
@@ -0,0 +1,30 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package model
+
+// Alarm is a single CloudWatch alarm returned by a DescribeAlarms discovery pass.
+type Alarm struct {
+	ARN         string
+	AlarmName   string
+	Namespace   string
+	MetricName  string
+	State       string
+	StateReason string
+}
+
+// AlarmResult is one job's DescribeAlarms results for a single region/account, consumed by
+// promutil.BuildAlarmStateMetrics.
+type AlarmResult struct {
+	Context *ScrapeContext
+	Data    []*Alarm
+}
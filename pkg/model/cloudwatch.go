@@ -0,0 +1,91 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package model
+
+import "time"
+
+// ScrapeContext carries the account/region identity a batch of CloudWatch results was
+// collected under, plus any custom tags the job configured, so that account/region/custom-tag
+// labels don't need to be threaded through every CloudwatchData/TaggedResource individually.
+type ScrapeContext struct {
+	Region       string
+	AccountID    string
+	AccountAlias string
+	CustomTags   []Tag
+}
+
+// CloudwatchMetricResult is one job's GetMetricData results for a single region/account,
+// ready to be turned into PrometheusMetric values by promutil.BuildMetrics.
+type CloudwatchMetricResult struct {
+	Context *ScrapeContext
+	Data    []*CloudwatchData
+}
+
+// MetricMigrationParams carries the per-metric behavior flags (config.Metric/
+// config.JobLevelMetricFields, already resolved to their effective value) that
+// promutil.BuildMetrics needs but that aren't properties of the datapoint itself.
+type MetricMigrationParams struct {
+	NilToZero              bool
+	AddCloudwatchTimestamp bool
+	// RateOverPreviousPeriod marks this series as eligible for promutil.ApplyRateTracking to
+	// replace its value with a per-second rate computed against the previous scrape.
+	RateOverPreviousPeriod bool
+}
+
+// GetMetricDataResult is the single datapoint GetMetricData returned for one metric/statistic,
+// already reduced to the latest value (see Datapoint/sortByTimestamp for the pre-reduction
+// multi-datapoint shape GetMetricStatistics callers deal with).
+type GetMetricDataResult struct {
+	Statistic string
+	Datapoint *float64
+	Timestamp time.Time
+	// StartTimestamp is set by promutil.ApplyStartTimeTracking for series classified as
+	// counters, so the PrometheusMetric built from this result can carry a stable start time
+	// across scrapes instead of the counter appearing to restart at every scrape.
+	StartTimestamp time.Time
+}
+
+// Datapoint is a single CloudWatch GetMetricStatistics datapoint, before it has been reduced
+// to the single most-recent value a GetMetricDataResult carries.
+type Datapoint struct {
+	Timestamp *time.Time
+	Average   *float64
+	Minimum   *float64
+	Maximum   *float64
+	Sum       *float64
+}
+
+// CloudwatchData is everything promutil.BuildMetrics/BuildEMFLogs/GroupByResource need to turn
+// a single scraped CloudWatch metric into output: its identity (namespace, metric name,
+// dimensions, owning resource) and the datapoint GetMetricData returned for it.
+type CloudwatchData struct {
+	MetricName            string
+	MetricMigrationParams MetricMigrationParams
+	Namespace             string
+	GetMetricDataResult   *GetMetricDataResult
+	Dimensions            []Dimension
+	ResourceName          string
+	Tags                  []Tag
+}
+
+// ResourceMetricGroup batches every CloudwatchData entry sharing the same namespace, resource,
+// dimension set and tag set, so a label map for the group can be built once instead of once
+// per metric. See promutil.GroupByResource.
+type ResourceMetricGroup struct {
+	Namespace    string
+	ResourceName string
+	Dimensions   []Dimension
+	Tags         []Tag
+	Context      *ScrapeContext
+	Metrics      []*CloudwatchData
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model holds the validated, config-independent representation that pkg/config
+// converts YAML into and that the rest of the exporter (discovery, scraping, metric building)
+// operates on, so that those packages never need to import pkg/config themselves.
+package model
+
+import (
+	"time"
+
+	"github.com/grafana/regexp"
+)
+
+// DefaultPeriodSeconds and DefaultLengthSeconds are the fallbacks a metric's Period/Length
+// take when neither the metric nor its job level config set one.
+const (
+	DefaultPeriodSeconds = 300
+	DefaultLengthSeconds = 300
+)
+
+// JobsConfig is the fully validated, flattened configuration produced by
+// config.ScrapeConf.Validate/Load/Reload. It is what the scraping/collection code is actually
+// built against, instead of the YAML-shaped config.ScrapeConf.
+type JobsConfig struct {
+	StsRegion           string
+	DecoupledScraping   DecoupledScraping
+	DiscoveryJobs       []DiscoveryJob
+	StaticJobs          []StaticJob
+	CustomNamespaceJobs []CustomNamespaceJob
+	// CounterNamespaces lists the namespaces whose `Sum` statistic is monotonic and should
+	// therefore be exposed with a stable start time rather than as a plain gauge; see
+	// promutil/starttime.NewNamespaceClassifier, which this is built for.
+	CounterNamespaces []string
+}
+
+// DecoupledScraping mirrors config.DecoupledScraping; see pkg/scheduler.Cache for the
+// goroutine and cache it describes.
+type DecoupledScraping struct {
+	Enabled        bool
+	ScrapeInterval time.Duration
+	Jitter         time.Duration
+}
+
+// MetricConfig is a single CloudWatch metric to collect, with its job-level defaults already
+// resolved (statistics, period, length, nilToZero, ...).
+type MetricConfig struct {
+	Name                   string
+	Statistics             []string
+	Period                 int64
+	Length                 int64
+	Delay                  int64
+	NilToZero              bool
+	AddCloudwatchTimestamp bool
+	// RateOverPreviousPeriod replaces this metric's value with a per-second rate computed from
+	// the current and previous scrape's datapoint; no separate `_rate` series is exposed.
+	RateOverPreviousPeriod bool
+}
+
+// TopK mirrors config.TopK / promutil.TopKConfig: the optional top-K post-processing stage
+// applied to a job's output after EnsureLabelConsistencyAndRemoveDuplicates.
+type TopK struct {
+	Limit            int
+	Order            string
+	GroupBy          []string
+	OtherAggregation string
+}
+
+// DiscoveryJob scrapes every resource of a given AWS service namespace that the job's
+// SearchTags/dimension requirements match, tagging each one with its resource metadata.
+type DiscoveryJob struct {
+	Regions                     []string
+	Namespace                   string
+	DimensionNameRequirements   []string
+	RecentlyActiveOnly          bool
+	RoundingPeriod              *int64
+	Roles                       []Role
+	SearchTags                  []SearchTag
+	CustomTags                  []Tag
+	Metrics                     []*MetricConfig
+	IncludeContextOnInfoMetrics bool
+	IncludeLinkedAccounts       []string
+	DimensionsRegexps           []*regexp.Regexp
+	ScrapeInterval              time.Duration
+	AWSSdkVersion               string
+	TopK                        TopK
+	ExportedTagsOnMetrics       []string
+}
+
+// StaticJob scrapes a single, explicitly-dimensioned resource rather than discovering
+// resources in a namespace.
+type StaticJob struct {
+	Name           string
+	Namespace      string
+	Regions        []string
+	Roles          []Role
+	CustomTags     []Tag
+	Dimensions     []Dimension
+	Metrics        []*MetricConfig
+	ScrapeInterval time.Duration
+	AWSSdkVersion  string
+}
+
+// CustomNamespaceJob scrapes a custom (non-AWS) CloudWatch namespace, e.g. metrics an
+// application publishes itself via PutMetricData.
+type CustomNamespaceJob struct {
+	Regions                   []string
+	Name                      string
+	Namespace                 string
+	DimensionNameRequirements []string
+	RoundingPeriod            *int64
+	RecentlyActiveOnly        bool
+	Roles                     []Role
+	CustomTags                []Tag
+	Metrics                   []*MetricConfig
+	IncludeLinkedAccounts     []string
+	ScrapeInterval            time.Duration
+	AWSSdkVersion             string
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package model
+
+// Role describes an IAM role to assume before making AWS API calls for a job, mirroring
+// config.Role. An empty Role means "use the credentials the exporter is already running
+// with" rather than assuming anything.
+type Role struct {
+	RoleArn              string
+	ExternalID           string
+	SessionName          string
+	DurationSeconds      int
+	SessionTags          map[string]string
+	TransitiveTagKeys    []string
+	SourceIdentity       string
+	WebIdentityTokenFile string
+	// ChainedRoles are assumed in order after this role, each one using the credentials
+	// produced by the role before it.
+	ChainedRoles []Role
+}
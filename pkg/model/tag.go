@@ -0,0 +1,34 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package model
+
+import "github.com/grafana/regexp"
+
+// Tag is a resource tag or a job-level custom tag, carried through from config.Tag.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// SearchTag is a resource tag filter: a resource is only scraped by a Discovery job if it has
+// a tag named Key whose value matches Value.
+type SearchTag struct {
+	Key   string
+	Value *regexp.Regexp
+}
+
+// Dimension is a CloudWatch metric dimension name/value pair.
+type Dimension struct {
+	Name  string
+	Value string
+}
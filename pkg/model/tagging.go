@@ -0,0 +1,35 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package model
+
+// TaggedResource is a single AWS resource discovered by a Discovery job's resource-tagging
+// pass, along with the tags it matched on.
+type TaggedResource struct {
+	ARN       string
+	Namespace string
+	Region    string
+	Tags      []Tag
+}
+
+// TaggedResourceResult is one job's discovered resources for a single region/account,
+// consumed by promutil.BuildNamespaceInfoMetrics to build `aws_<namespace>_info` metrics.
+type TaggedResourceResult struct {
+	Context *ScrapeContext
+	Data    []*TaggedResource
+}
+
+// LabelSet is the set of label keys observed across every PrometheusMetric built for a given
+// metric name, used by promutil.EnsureLabelConsistencyAndRemoveDuplicates to backfill metrics
+// that are missing a label another metric with the same name has, so Prometheus doesn't reject
+// the scrape for an inconsistent label set.
+type LabelSet map[string]struct{}
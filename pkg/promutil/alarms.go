@@ -0,0 +1,83 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"log/slog"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// BuildAlarmStateMetrics turns DescribeAlarms results into annotation-style
+// `aws_<service>_alarm_info{alarm_name, state, reason, metric_name, ...}` samples, joined to
+// the same `name=<arn>` label that BuildNamespaceInfoMetrics uses for tag info metrics. This
+// lets dashboards draw alarm state-change annotations over CloudWatch panels the same way an
+// external events datasource draws deploy markers.
+func BuildAlarmStateMetrics(
+	alarmResults []model.AlarmResult,
+	metrics []*PrometheusMetric,
+	observedMetricLabels map[string]model.LabelSet,
+	labelsSnakeCase bool,
+	logger *slog.Logger,
+) ([]*PrometheusMetric, map[string]model.LabelSet) {
+	for _, result := range alarmResults {
+		for _, alarm := range result.Data {
+			if alarm.ARN == "" {
+				logger.Warn("skipping alarm with no ARN", "alarm_name", alarm.AlarmName, "namespace", alarm.Namespace)
+				continue
+			}
+			metricName := alarmMetricName(alarm.Namespace)
+
+			labels := map[string]string{
+				"name":        alarm.ARN,
+				"alarm_name":  alarm.AlarmName,
+				"state":       alarm.State,
+				"reason":      alarm.StateReason,
+				"metric_name": alarm.MetricName,
+			}
+
+			if result.Context != nil {
+				labels["account_id"] = result.Context.AccountID
+				labels["region"] = result.Context.Region
+				for _, tag := range result.Context.CustomTags {
+					key := "custom_tag_" + tag.Key
+					if labelsSnakeCase {
+						key = toSnakeCase(key)
+					}
+					labels[key] = tag.Value
+				}
+			}
+
+			if labelsSnakeCase {
+				labels = snakeCaseKeys(labels)
+			}
+
+			metrics = append(metrics, &PrometheusMetric{
+				Name:   metricName,
+				Labels: labels,
+				Value:  0,
+			})
+
+			observedMetricLabels[metricName] = mergeLabelSet(observedMetricLabels[metricName], labels)
+		}
+	}
+
+	return metrics, observedMetricLabels
+}
+
+// alarmMetricName is BuildAlarmStateMetrics' namespace-to-metric-name mapping: the same
+// "aws_<namespace>" prefix every other metric/info series in this package uses, suffixed with
+// "_alarm_info" instead of "_info" or a statistic.
+func alarmMetricName(namespace string) string {
+	return namespacePrefix(namespace) + "_alarm_info"
+}
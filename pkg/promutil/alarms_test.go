@@ -0,0 +1,105 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestBuildAlarmStateMetrics(t *testing.T) {
+	type testCase struct {
+		name                 string
+		alarms               []model.AlarmResult
+		observedMetricLabels map[string]model.LabelSet
+		labelsSnakeCase      bool
+		expectedMetrics      []*PrometheusMetric
+	}
+
+	testCases := []testCase{
+		{
+			name: "alarm in alarm state",
+			alarms: []model.AlarmResult{
+				{
+					Context: &model.ScrapeContext{
+						Region:    "us-east-1",
+						AccountID: "123456789012",
+					},
+					Data: []*model.Alarm{
+						{
+							ARN:         "arn:aws:cloudwatch:us-east-1:123456789012:alarm:high-cpu",
+							AlarmName:   "high-cpu",
+							Namespace:   "AWS/EC2",
+							MetricName:  "CPUUtilization",
+							State:       "ALARM",
+							StateReason: "Threshold Crossed",
+						},
+					},
+				},
+			},
+			observedMetricLabels: map[string]model.LabelSet{},
+			labelsSnakeCase:      false,
+			expectedMetrics: []*PrometheusMetric{
+				{
+					Name: "aws_ec2_alarm_info",
+					Labels: map[string]string{
+						"name":        "arn:aws:cloudwatch:us-east-1:123456789012:alarm:high-cpu",
+						"alarm_name":  "high-cpu",
+						"state":       "ALARM",
+						"reason":      "Threshold Crossed",
+						"metric_name": "CPUUtilization",
+						"account_id":  "123456789012",
+						"region":      "us-east-1",
+					},
+					Value: 0,
+				},
+			},
+		},
+		{
+			name: "alarm with no ARN is skipped",
+			alarms: []model.AlarmResult{
+				{
+					Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+					Data: []*model.Alarm{
+						{
+							AlarmName:   "no-arn",
+							Namespace:   "AWS/EC2",
+							MetricName:  "CPUUtilization",
+							State:       "ALARM",
+							StateReason: "Threshold Crossed",
+						},
+					},
+				},
+			},
+			observedMetricLabels: map[string]model.LabelSet{},
+			labelsSnakeCase:      false,
+			expectedMetrics:      []*PrometheusMetric{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics, _ := BuildAlarmStateMetrics(tc.alarms, []*PrometheusMetric{}, tc.observedMetricLabels, tc.labelsSnakeCase, promslog.NewNopLogger())
+			require.Equal(t, tc.expectedMetrics, metrics)
+		})
+	}
+}
+
+func TestAlarmMetricName(t *testing.T) {
+	require.Equal(t, "aws_ec2_alarm_info", alarmMetricName("AWS/EC2"))
+	require.Equal(t, "aws_sagemaker_trainingjobs_alarm_info", alarmMetricName("/aws/sagemaker/TrainingJobs"))
+}
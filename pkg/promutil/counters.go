@@ -0,0 +1,77 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"strings"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil/starttime"
+)
+
+// ApplyStartTimeTracking runs data through adjuster before BuildMetrics sees it, so that every
+// CloudwatchData entry adjuster classifies as a counter (see starttime.Classifier) has its
+// GetMetricDataResult.StartTimestamp stamped with the series' first-observed time - BuildMetrics
+// copies that straight onto the PrometheusMetric it builds. It returns one stale-marker
+// PrometheusMetric per series that was tracked on a previous call but is missing from data this
+// time, so a resource that disappeared from CloudWatch between scrapes is reported once as
+// stale instead of silently vanishing; callers should append these to BuildMetrics' output.
+func ApplyStartTimeTracking(data []model.CloudwatchMetricResult, adjuster *starttime.Adjuster) []*PrometheusMetric {
+	seenThisScrape := map[string]struct{}{}
+
+	for _, result := range data {
+		for _, d := range result.Data {
+			if d.GetMetricDataResult == nil {
+				continue
+			}
+
+			name := BuildMetricName(d.Namespace, d.MetricName, d.GetMetricDataResult.Statistic)
+			fp := counterFingerprint(name, result, d)
+			seenThisScrape[fp] = struct{}{}
+
+			value := 0.0
+			if d.GetMetricDataResult.Datapoint != nil {
+				value = *d.GetMetricDataResult.Datapoint
+			}
+
+			start, isCounter := adjuster.Observe(fp, d.Namespace, d.GetMetricDataResult.Statistic, d.GetMetricDataResult.Timestamp, value)
+			if isCounter {
+				d.GetMetricDataResult.StartTimestamp = start
+			}
+		}
+	}
+
+	var stale []*PrometheusMetric
+	for _, fp := range adjuster.Missing(seenThisScrape) {
+		name, _, _ := strings.Cut(fp, "|")
+		stale = append(stale, &PrometheusMetric{Name: name, Labels: map[string]string{}, Value: starttime.StaleNaN})
+	}
+	return stale
+}
+
+// counterFingerprint builds a stable per-series key, prefixed with the metric's final
+// Prometheus name so a stale marker can be emitted under that name without re-deriving it.
+func counterFingerprint(name string, result model.CloudwatchMetricResult, d *model.CloudwatchData) string {
+	var accountID, region string
+	if result.Context != nil {
+		accountID = result.Context.AccountID
+		region = result.Context.Region
+	}
+
+	dimNames := make([]string, 0, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		dimNames = append(dimNames, dim.Name+"="+dim.Value)
+	}
+
+	return strings.Join([]string{name, accountID, region, d.ResourceName, strings.Join(dimNames, ",")}, "|")
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil/starttime"
+)
+
+func albRequestCount(datapoint float64, timestamp time.Time) []model.CloudwatchMetricResult {
+	return []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{{
+			MetricName:          "RequestCount",
+			Namespace:           "AWS/ApplicationELB",
+			ResourceName:        "app/my-alb/50dc6c495c0c9188",
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(datapoint), Timestamp: timestamp},
+		}},
+	}}
+}
+
+func TestApplyStartTimeTracking_StampsCounterStartTimestamp(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	adjuster := starttime.New(time.Hour, starttime.NewNamespaceClassifier(map[string]bool{"AWS/ApplicationELB": true}))
+
+	first := albRequestCount(10, ts)
+	stale := ApplyStartTimeTracking(first, adjuster)
+	require.Empty(t, stale)
+	require.Equal(t, ts, first[0].Data[0].GetMetricDataResult.StartTimestamp)
+
+	// A later scrape of the same series keeps the original StartTimestamp rather than resetting
+	// it to the new scrape's timestamp.
+	second := albRequestCount(25, ts.Add(5*time.Minute))
+	stale = ApplyStartTimeTracking(second, adjuster)
+	require.Empty(t, stale)
+	require.Equal(t, ts, second[0].Data[0].GetMetricDataResult.StartTimestamp)
+}
+
+func TestApplyStartTimeTracking_GaugeIsNotStamped(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	adjuster := starttime.New(time.Hour, starttime.NewNamespaceClassifier(map[string]bool{}))
+
+	data := albRequestCount(10, ts)
+	ApplyStartTimeTracking(data, adjuster)
+	require.True(t, data[0].Data[0].GetMetricDataResult.StartTimestamp.IsZero())
+}
+
+func TestApplyStartTimeTracking_MissingSeriesProducesOneStaleMarker(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	adjuster := starttime.New(time.Hour, starttime.NewNamespaceClassifier(map[string]bool{"AWS/ApplicationELB": true}))
+
+	ApplyStartTimeTracking(albRequestCount(10, ts), adjuster)
+
+	stale := ApplyStartTimeTracking(nil, adjuster)
+	require.Len(t, stale, 1)
+	require.Equal(t, "aws_applicationelb_request_count_sum", stale[0].Name)
+	require.True(t, math.IsNaN(stale[0].Value))
+
+	// The same missing series isn't re-reported as stale on a following scrape.
+	stale = ApplyStartTimeTracking(nil, adjuster)
+	require.Empty(t, stale)
+}
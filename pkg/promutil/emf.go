@@ -0,0 +1,156 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// timestampBucket is the grouping granularity: metrics for the same resource reported within
+// the same minute become one EMF record.
+const timestampBucket = time.Minute
+
+// BuildEMFLogs converts the same CloudwatchMetricResult input accepted by BuildMetrics into
+// CloudWatch Embedded Metric Format (EMF) log records, one JSON object per line. Entries that
+// share an account, region, namespace, dimension set, and one-minute timestamp bucket for the
+// same resource are grouped into a single record with one numeric field per metric, mirroring
+// the batching technique used by AWS's own EMF exporters. Account and region are part of the
+// grouping key, not just output fields, because Static/CustomNamespace jobs don't guarantee
+// ResourceName is ARN-shaped or globally unique - without them, two different accounts
+// reporting the same ResourceName would silently collapse into one record. Tag/custom-tag
+// labels and account_id/region/name are emitted as non-dimension context fields on the record,
+// so a downstream consumer (YACE feeding CloudWatch Logs or a Firehose stream) sees one record
+// per resource per minute instead of one per metric.
+func BuildEMFLogs(data []model.CloudwatchMetricResult) ([]byte, error) {
+	groups := map[string]*emfGroup{}
+	var order []string
+
+	for _, result := range data {
+		for _, d := range result.Data {
+			if d.GetMetricDataResult == nil || d.GetMetricDataResult.Datapoint == nil {
+				continue
+			}
+
+			key := emfGroupKey(result, d)
+			group, ok := groups[key]
+			if !ok {
+				group = newEMFGroup(result, d)
+				groups[key] = group
+				order = append(order, key)
+			}
+			group.metrics[d.MetricName] = *d.GetMetricDataResult.Datapoint
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, key := range order {
+		record, err := groups[key].toRecord()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal EMF record: %w", err)
+		}
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// emfGroup accumulates the metrics reported for a single (account, region, namespace, resource,
+// dimension set, timestamp bucket) key before it is flattened into one EMF record.
+type emfGroup struct {
+	namespace  string
+	resource   string
+	dimensions []model.Dimension
+	tags       []model.Tag
+	timestamp  int64 // unix millis, truncated to the minute
+	metrics    map[string]float64
+	context    *model.ScrapeContext
+}
+
+func newEMFGroup(result model.CloudwatchMetricResult, d *model.CloudwatchData) *emfGroup {
+	return &emfGroup{
+		namespace:  d.Namespace,
+		resource:   d.ResourceName,
+		dimensions: d.Dimensions,
+		tags:       d.Tags,
+		timestamp:  d.GetMetricDataResult.Timestamp.Truncate(timestampBucket).UnixMilli(),
+		metrics:    map[string]float64{},
+		context:    result.Context,
+	}
+}
+
+func emfGroupKey(result model.CloudwatchMetricResult, d *model.CloudwatchData) string {
+	names := make([]string, 0, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		names = append(names, dim.Name+"="+dim.Value)
+	}
+	sort.Strings(names)
+
+	var accountID, region string
+	if result.Context != nil {
+		accountID = result.Context.AccountID
+		region = result.Context.Region
+	}
+
+	bucket := d.GetMetricDataResult.Timestamp.Truncate(timestampBucket).Unix()
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d", accountID, region, d.Namespace, d.ResourceName, strings.Join(names, ","), bucket)
+}
+
+func (g *emfGroup) toRecord() ([]byte, error) {
+	dimensionNames := make([]string, 0, len(g.dimensions))
+	record := map[string]interface{}{}
+
+	for _, dim := range g.dimensions {
+		dimensionNames = append(dimensionNames, dim.Name)
+		record[dim.Name] = dim.Value
+	}
+
+	metricDefs := make([]map[string]string, 0, len(g.metrics))
+	for name, value := range g.metrics {
+		metricDefs = append(metricDefs, map[string]string{"Name": name})
+		record[name] = value
+	}
+	sort.Slice(metricDefs, func(i, j int) bool { return metricDefs[i]["Name"] < metricDefs[j]["Name"] })
+
+	record["_aws"] = map[string]interface{}{
+		"Timestamp": g.timestamp,
+		"CloudWatchMetrics": []map[string]interface{}{
+			{
+				"Namespace":  g.namespace,
+				"Dimensions": [][]string{dimensionNames},
+				"Metrics":    metricDefs,
+			},
+		},
+	}
+	record["name"] = g.resource
+	for _, tag := range g.tags {
+		record["tag_"+tag.Key] = tag.Value
+	}
+
+	if g.context != nil {
+		record["account_id"] = g.context.AccountID
+		record["region"] = g.context.Region
+		for _, tag := range g.context.CustomTags {
+			record["custom_tag_"+tag.Key] = tag.Value
+		}
+	}
+
+	return json.Marshal(record)
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestBuildEMFLogs_GroupsSameResourceIntoOneRecord(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 30, 0, time.UTC)
+
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{
+			Region:    "us-east-1",
+			AccountID: "123456789012",
+		},
+		Data: []*model.CloudwatchData{
+			{
+				MetricName:   "CPUUtilization",
+				Namespace:    "AWS/ElastiCache",
+				ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+				Dimensions:   []model.Dimension{{Name: "CacheClusterId", Value: "redis-cluster"}},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic: "Average",
+					Datapoint: aws.Float64(1),
+					Timestamp: ts,
+				},
+			},
+			{
+				MetricName:   "FreeableMemory",
+				Namespace:    "AWS/ElastiCache",
+				ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+				Dimensions:   []model.Dimension{{Name: "CacheClusterId", Value: "redis-cluster"}},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic: "Average",
+					Datapoint: aws.Float64(2),
+					Timestamp: ts,
+				},
+			},
+		},
+	}}
+
+	out, err := BuildEMFLogs(data)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 1, "metrics for the same resource in the same minute should collapse to one record")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+
+	require.Equal(t, "redis-cluster", record["CacheClusterId"])
+	require.InDelta(t, 1.0, record["CPUUtilization"], 0.0001)
+	require.InDelta(t, 2.0, record["FreeableMemory"], 0.0001)
+	require.Equal(t, "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster", record["name"])
+	require.Equal(t, "123456789012", record["account_id"])
+
+	awsMeta, ok := record["_aws"].(map[string]interface{})
+	require.True(t, ok)
+	cwMetrics, ok := awsMeta["CloudWatchMetrics"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, cwMetrics, 1)
+}
+
+func TestBuildEMFLogs_SeparatesDifferentResources(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			{
+				MetricName:   "CPUUtilization",
+				Namespace:    "AWS/ElastiCache",
+				ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:a",
+				Dimensions:   []model.Dimension{{Name: "CacheClusterId", Value: "a"}},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic: "Average",
+					Datapoint: aws.Float64(1),
+					Timestamp: ts,
+				},
+			},
+			{
+				MetricName:   "CPUUtilization",
+				Namespace:    "AWS/ElastiCache",
+				ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:b",
+				Dimensions:   []model.Dimension{{Name: "CacheClusterId", Value: "b"}},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic: "Average",
+					Datapoint: aws.Float64(2),
+					Timestamp: ts,
+				},
+			},
+		},
+	}}
+
+	out, err := BuildEMFLogs(data)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 2)
+}
+
+func TestBuildEMFLogs_SeparatesSameResourceNameAcrossAccounts(t *testing.T) {
+	// CustomNamespace/Static jobs aren't guaranteed to report an ARN-shaped, globally-unique
+	// ResourceName, so two different accounts reporting under the same name must not collapse
+	// into a single EMF record.
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newResult := func(accountID string, value float64) model.CloudwatchMetricResult {
+		return model.CloudwatchMetricResult{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: accountID},
+			Data: []*model.CloudwatchData{
+				{
+					MetricName:   "QueueDepth",
+					Namespace:    "CustomQueues",
+					ResourceName: "main",
+					GetMetricDataResult: &model.GetMetricDataResult{
+						Statistic: "Average",
+						Datapoint: aws.Float64(value),
+						Timestamp: ts,
+					},
+				},
+			},
+		}
+	}
+
+	data := []model.CloudwatchMetricResult{newResult("111111111111", 1), newResult("222222222222", 2)}
+
+	out, err := BuildEMFLogs(data)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 2, "same ResourceName in different accounts must not be grouped together")
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// GroupByResource groups CloudwatchData entries that share the same account, region, namespace,
+// resource ARN, dimension set and tag set into a single model.ResourceMetricGroup. Four metrics
+// reported for the same ElastiCache cluster in the same scrape, for example, collapse into one
+// group instead of each independently producing a fresh Labels map - BuildMetrics builds that
+// shared label map once per group and fans out to per-metric PrometheusMetric values from it.
+func GroupByResource(results []model.CloudwatchMetricResult) []model.ResourceMetricGroup {
+	groups := map[string]*model.ResourceMetricGroup{}
+	var order []string
+
+	for _, result := range results {
+		for _, d := range result.Data {
+			key := resourceGroupKey(result, d)
+			group, ok := groups[key]
+			if !ok {
+				group = &model.ResourceMetricGroup{
+					Namespace:    d.Namespace,
+					ResourceName: d.ResourceName,
+					Dimensions:   d.Dimensions,
+					Tags:         d.Tags,
+					Context:      result.Context,
+				}
+				groups[key] = group
+				order = append(order, key)
+			}
+			group.Metrics = append(group.Metrics, d)
+		}
+	}
+
+	out := make([]model.ResourceMetricGroup, 0, len(order))
+	for _, key := range order {
+		out = append(out, *groups[key])
+	}
+	return out
+}
+
+// resourceGroupKey builds the (account, region, namespace, resource ARN, dimension fingerprint,
+// tag fingerprint) key that identifies a resource group, independent of the order
+// dimensions/tags were discovered in. Account and region are part of the key, not just labels
+// resourceGroupLabels later copies onto the group, because Static/CustomNamespace jobs don't
+// guarantee ResourceName is ARN-shaped or globally unique - without them, two different accounts
+// reporting the same ResourceName would silently collapse into one group (see emfGroupKey, which
+// has the same requirement for the same reason).
+func resourceGroupKey(result model.CloudwatchMetricResult, d *model.CloudwatchData) string {
+	dimNames := make([]string, 0, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		dimNames = append(dimNames, dim.Name+"="+dim.Value)
+	}
+	sort.Strings(dimNames)
+
+	tagNames := make([]string, 0, len(d.Tags))
+	for _, tag := range d.Tags {
+		tagNames = append(tagNames, tag.Key+"="+tag.Value)
+	}
+	sort.Strings(tagNames)
+
+	var accountID, region string
+	if result.Context != nil {
+		accountID = result.Context.AccountID
+		region = result.Context.Region
+	}
+
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", accountID, region, d.Namespace, d.ResourceName, strings.Join(dimNames, ","), strings.Join(tagNames, ","))
+}
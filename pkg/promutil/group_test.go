@@ -0,0 +1,109 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func fourMetricsSameCluster() []model.CloudwatchMetricResult {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dims := []model.Dimension{{Name: "CacheClusterId", Value: "redis-cluster"}}
+	tags := []model.Tag{{Key: "managed_by", Value: "terraform"}}
+
+	newData := func(metric string, value float64) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   metric,
+			Namespace:    "AWS/ElastiCache",
+			ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+			Dimensions:   dims,
+			Tags:         tags,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic: "Average",
+				Datapoint: aws.Float64(value),
+				Timestamp: ts,
+			},
+		}
+	}
+
+	return []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("CPUUtilization", 1),
+			newData("FreeableMemory", 2),
+			newData("NetworkBytesIn", 3),
+			newData("NetworkBytesOut", 4),
+		},
+	}}
+}
+
+func TestGroupByResource(t *testing.T) {
+	groups := GroupByResource(fourMetricsSameCluster())
+
+	require.Len(t, groups, 1, "all four metrics share the same namespace/resource/dimensions/tags")
+	require.Equal(t, "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster", groups[0].ResourceName)
+	require.Len(t, groups[0].Metrics, 4)
+}
+
+func TestGroupByResource_SeparatesDifferentDimensions(t *testing.T) {
+	data := fourMetricsSameCluster()
+	data[0].Data[0].Dimensions = []model.Dimension{{Name: "CacheClusterId", Value: "other-cluster"}}
+
+	groups := GroupByResource(data)
+	require.Len(t, groups, 2)
+}
+
+func BenchmarkGroupByResource(b *testing.B) {
+	data := fourMetricsSameCluster()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GroupByResource(data)
+	}
+}
+
+// BenchmarkNaivePerMetricLabels is a standalone comparison point: it measures building a fresh
+// Labels map per CloudwatchData entry even when several entries describe the same resource.
+// BenchmarkGroupByResource measures only the grouping step and builds no labels. See
+// Benchmark_BuildMetrics in migrate_test.go for the allocations of the real pipeline, which
+// calls GroupByResource internally.
+func BenchmarkNaivePerMetricLabels(b *testing.B) {
+	data := fourMetricsSameCluster()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, result := range data {
+			for _, d := range result.Data {
+				labels := map[string]string{
+					"name":   d.ResourceName,
+					"region": result.Context.Region,
+				}
+				for _, dim := range d.Dimensions {
+					labels["dimension_"+dim.Name] = dim.Value
+				}
+				for _, tag := range d.Tags {
+					labels["tag_"+tag.Key] = tag.Value
+				}
+				_ = labels
+			}
+		}
+	}
+}
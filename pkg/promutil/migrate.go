@@ -0,0 +1,307 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package promutil turns scraped CloudWatch data (model.CloudwatchMetricResult,
+// model.TaggedResourceResult, model.AlarmResult) into Prometheus samples. BuildMetrics is the
+// core entry point; BuildNamespaceInfoMetrics, BuildAlarmStateMetrics, BuildEMFLogs and
+// GroupByResource build alternate or supplementary representations from the same inputs.
+// ApplyRateTracking and ApplyStartTimeTracking rewrite CloudwatchMetricResult in place before
+// BuildMetrics runs; EnsureLabelConsistencyAndRemoveDuplicates and ApplyTopK post-process its
+// output. Pipeline composes all of these into the single sequence a `/metrics` handler needs.
+package promutil
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// PrometheusMetric is a single sample ready to be exposed: a metric name, its label set, a
+// value, and the CloudWatch timestamp it was reported for (used only when IncludeTimestamp is
+// set, since Prometheus normally prefers to stamp samples with scrape time).
+type PrometheusMetric struct {
+	Name             string
+	Labels           map[string]string
+	Value            float64
+	Timestamp        time.Time
+	IncludeTimestamp bool
+	// StartTimestamp is set for series ApplyStartTimeTracking classified as counters, via
+	// GetMetricDataResult.StartTimestamp; it is the zero time for every other series.
+	StartTimestamp time.Time
+}
+
+// BuildMetrics turns a job's GetMetricData results into PrometheusMetric values, one per
+// CloudwatchData entry, plus the observed label set for every metric name so that
+// EnsureLabelConsistencyAndRemoveDuplicates can backfill metrics that are missing a label a
+// sibling metric of the same name has. Entries with no GetMetricDataResult, or with no datapoint
+// while AddCloudwatchTimestamp is requested (whatever NilToZero says), are skipped: a
+// synthesized value carrying a real CloudWatch timestamp would misrepresent a period
+// CloudWatch never actually reported on.
+func BuildMetrics(data []model.CloudwatchMetricResult, labelsSnakeCase bool, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+	metrics := make([]*PrometheusMetric, 0, len(data))
+	observedMetricLabels := map[string]model.LabelSet{}
+
+	for _, group := range GroupByResource(data) {
+		base := resourceGroupLabels(group)
+
+		for _, d := range group.Metrics {
+			if d.GetMetricDataResult == nil {
+				continue
+			}
+			if d.GetMetricDataResult.Datapoint == nil && d.MetricMigrationParams.AddCloudwatchTimestamp {
+				logger.Debug("skipping metric with no datapoint while addCloudwatchTimestamp is set", "metric_name", d.MetricName, "resource_name", d.ResourceName)
+				continue
+			}
+
+			value := math.NaN()
+			switch {
+			case d.GetMetricDataResult.Datapoint != nil:
+				value = *d.GetMetricDataResult.Datapoint
+			case d.MetricMigrationParams.NilToZero:
+				value = 0
+			}
+
+			labels := make(map[string]string, len(base))
+			for k, v := range base {
+				labels[k] = v
+			}
+			if labelsSnakeCase {
+				labels = snakeCaseKeys(labels)
+			}
+
+			name := BuildMetricName(d.Namespace, d.MetricName, d.GetMetricDataResult.Statistic)
+			metrics = append(metrics, &PrometheusMetric{
+				Name:             name,
+				Labels:           labels,
+				Value:            value,
+				Timestamp:        d.GetMetricDataResult.Timestamp,
+				IncludeTimestamp: d.MetricMigrationParams.AddCloudwatchTimestamp,
+				StartTimestamp:   d.GetMetricDataResult.StartTimestamp,
+			})
+
+			observedMetricLabels[name] = mergeLabelSet(observedMetricLabels[name], labels)
+		}
+	}
+
+	return metrics, observedMetricLabels, nil
+}
+
+// resourceGroupLabels builds the label map shared by every metric reported for the same
+// resource in a scrape: building it once per group instead of once per CloudwatchData entry
+// is what lets GroupByResource actually reduce BuildMetrics' allocations.
+func resourceGroupLabels(group model.ResourceMetricGroup) map[string]string {
+	labels := map[string]string{"name": group.ResourceName}
+
+	if group.Context != nil {
+		labels["account_id"] = group.Context.AccountID
+		if group.Context.AccountAlias != "" {
+			labels["account_alias"] = group.Context.AccountAlias
+		}
+		labels["region"] = group.Context.Region
+		for _, tag := range group.Context.CustomTags {
+			labels["custom_tag_"+tag.Key] = tag.Value
+		}
+	}
+	for _, dim := range group.Dimensions {
+		labels["dimension_"+dim.Name] = dim.Value
+	}
+	for _, tag := range group.Tags {
+		labels["tag_"+tag.Key] = tag.Value
+	}
+
+	return labels
+}
+
+// BuildNamespaceInfoMetrics builds one `aws_<namespace>_info` metric per tagged resource,
+// appending to metrics/observedMetricLabels rather than replacing them so that it can be
+// called after BuildMetrics and contribute info series alongside the metrics it already built.
+func BuildNamespaceInfoMetrics(
+	resources []model.TaggedResourceResult,
+	metrics []*PrometheusMetric,
+	observedMetricLabels map[string]model.LabelSet,
+	labelsSnakeCase bool,
+	logger *slog.Logger,
+) ([]*PrometheusMetric, map[string]model.LabelSet) {
+	for _, result := range resources {
+		for _, r := range result.Data {
+			if r.ARN == "" {
+				logger.Warn("skipping tagged resource with no ARN", "namespace", r.Namespace)
+				continue
+			}
+
+			labels := map[string]string{"name": r.ARN}
+			for _, tag := range r.Tags {
+				labels["tag_"+tag.Key] = tag.Value
+			}
+			if result.Context != nil {
+				labels["account_id"] = result.Context.AccountID
+				if result.Context.AccountAlias != "" {
+					labels["account_alias"] = result.Context.AccountAlias
+				}
+				labels["region"] = result.Context.Region
+				for _, tag := range result.Context.CustomTags {
+					labels["custom_tag_"+tag.Key] = tag.Value
+				}
+			}
+			if labelsSnakeCase {
+				labels = snakeCaseKeys(labels)
+			}
+
+			name := namespacePrefix(r.Namespace) + "_info"
+			metrics = append(metrics, &PrometheusMetric{
+				Name:   name,
+				Labels: labels,
+				Value:  0,
+			})
+
+			observedMetricLabels[name] = mergeLabelSet(observedMetricLabels[name], labels)
+		}
+	}
+
+	return metrics, observedMetricLabels
+}
+
+func mergeLabelSet(labelSet model.LabelSet, labels map[string]string) model.LabelSet {
+	if labelSet == nil {
+		labelSet = make(model.LabelSet, len(labels))
+	}
+	for label := range labels {
+		labelSet[label] = struct{}{}
+	}
+	return labelSet
+}
+
+// EnsureLabelConsistencyAndRemoveDuplicates backfills every metric with an empty-string value
+// for any label key its name's observed label set has but the metric itself is missing - so
+// all series sharing a metric name expose the same label set, which Prometheus requires - and
+// then drops exact Name+Labels duplicates, which show up when the same metric+resource is
+// scraped more than once in a period (e.g. overlapping discovery and static jobs).
+func EnsureLabelConsistencyAndRemoveDuplicates(metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet) []*PrometheusMetric {
+	seen := make(map[string]bool, len(metrics))
+	out := make([]*PrometheusMetric, 0, len(metrics))
+
+	for _, m := range metrics {
+		for label := range observedMetricLabels[m.Name] {
+			if _, ok := m.Labels[label]; !ok {
+				m.Labels[label] = ""
+			}
+		}
+
+		fp := metricFingerprint(m)
+		if seen[fp] {
+			continue
+		}
+		seen[fp] = true
+		out = append(out, m)
+	}
+
+	return out
+}
+
+func metricFingerprint(m *PrometheusMetric) string {
+	keys := make([]string, 0, len(m.Labels))
+	for k := range m.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(m.Name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "|%s=%s", k, m.Labels[k])
+	}
+	return b.String()
+}
+
+// sortByTimestamp sorts CloudWatch datapoints newest-first, so callers that only want the most
+// recent value (BuildMetrics' GetMetricDataResult is already reduced to one, but
+// GetMetricStatistics-based callers are not) can just take index 0.
+func sortByTimestamp(datapoints []*model.Datapoint) []*model.Datapoint {
+	sort.SliceStable(datapoints, func(i, j int) bool {
+		return datapoints[i].Timestamp.After(*datapoints[j].Timestamp)
+	})
+	return datapoints
+}
+
+// namespacePrefix normalizes a CloudWatch namespace into the "aws_<namespace>" prefix shared
+// by every metric and info series this package builds, e.g. "AWS/ElastiCache" ->
+// "aws_elasticache" and "/aws/sagemaker/TrainingJobs" -> "aws_sagemaker_trainingjobs". A
+// namespace that already stringifies to an "aws_"-prefixed form (e.g. a leading-slash path
+// whose first segment is "aws") isn't prefixed twice.
+func namespacePrefix(namespace string) string {
+	ns := strings.TrimPrefix(namespace, "AWS/")
+	ns = strings.TrimPrefix(ns, "/")
+	ns = strings.ToLower(strings.ReplaceAll(ns, "/", "_"))
+	if !strings.HasPrefix(ns, "aws_") {
+		ns = "aws_" + ns
+	}
+	return ns
+}
+
+// BuildMetricName builds the final Prometheus metric name for a CloudWatch metric:
+// "aws_<namespace>_<metric>_<statistic>". A dot-separated metric name (e.g. Glue's
+// "driver.aggregate.bytesRead") has each segment snake-cased independently and joined with
+// underscores; if its first segment duplicates the namespace (case-insensitively), that
+// segment is dropped so the namespace isn't repeated in the name twice.
+func BuildMetricName(namespace, metric, statistic string) string {
+	prefix := namespacePrefix(namespace)
+	bareNamespace := strings.TrimPrefix(prefix, "aws_")
+
+	segments := strings.Split(metric, ".")
+	if len(segments) > 1 && strings.EqualFold(segments[0], bareNamespace) {
+		segments = segments[1:]
+	}
+	for i, seg := range segments {
+		segments[i] = toSnakeCase(seg)
+	}
+
+	return fmt.Sprintf("%s_%s_%s", prefix, strings.Join(segments, "_"), strings.ToLower(statistic))
+}
+
+// toSnakeCase lowercases s, replacing spaces and hyphens with underscores and splitting
+// camelCase words at a lowercase-to-uppercase boundary. A run of uppercase letters (an
+// acronym, e.g. the "CPU" in "CPUUtilization") is not split from the word it runs into, since
+// there is no lowercase-to-uppercase boundary inside it to split at.
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 && unicode.IsLower(runes[i-1]) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// snakeCaseKeys returns a copy of labels with every key passed through toSnakeCase.
+func snakeCaseKeys(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[toSnakeCase(k)] = v
+	}
+	return out
+}
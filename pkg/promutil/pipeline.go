@@ -0,0 +1,99 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil/starttime"
+)
+
+// Pipeline is the single call site that ties BuildMetrics together with the post-processing
+// stages this package offers, so a caller doesn't need to remember the order rate tracking,
+// metric building and the rest must run in. It is built up incrementally as those stages gain
+// real wiring.
+type Pipeline struct {
+	RateTracker *RateTracker
+	Adjuster    *starttime.Adjuster
+	TopK        TopKConfig
+}
+
+// NewPipeline builds a Pipeline whose RateTracker and starttime.Adjuster are both seeded with a
+// ttl of 2x scrapeInterval - see NewRateTracker and starttime.New's own docs for why that
+// multiple is the right default; the RateTracker also uses that same value as its maxGap. counterNamespaces is jobsCfg.CounterNamespaces turned into the
+// set starttime.NewNamespaceClassifier expects. topK is a job's model.TopK (e.g.
+// DiscoveryJob.TopK), converted to the TopKConfig ApplyTopK takes; a zero model.TopK leaves
+// top-K disabled, matching ApplyTopK's own "Limit <= 0 disables it" rule.
+func NewPipeline(scrapeInterval time.Duration, counterNamespaces []string, topK model.TopK) *Pipeline {
+	ttl := 2 * scrapeInterval
+
+	counters := make(map[string]bool, len(counterNamespaces))
+	for _, ns := range counterNamespaces {
+		counters[ns] = true
+	}
+
+	return &Pipeline{
+		RateTracker: NewRateTracker(ttl, ttl),
+		Adjuster:    starttime.New(ttl, starttime.NewNamespaceClassifier(counters)),
+		TopK: TopKConfig{
+			Limit:            topK.Limit,
+			Order:            topK.Order,
+			GroupBy:          topK.GroupBy,
+			OtherAggregation: topK.OtherAggregation,
+		},
+	}
+}
+
+// Run rewrites metricResults in place via ApplyRateTracking and ApplyStartTimeTracking, builds
+// PrometheusMetric values from it via BuildMetrics, then appends the tag-info and
+// alarm-state-info metrics BuildNamespaceInfoMetrics/BuildAlarmStateMetrics build from the same
+// scrape's tagging and alarm results, plus any stale markers ApplyStartTimeTracking produced for
+// series that disappeared since the previous scrape. The combined set is backfilled/deduped via
+// EnsureLabelConsistencyAndRemoveDuplicates and finally passed through ApplyTopK, so a
+// high-cardinality namespace's output is capped the same way regardless of whether its metrics
+// came from BuildMetrics, the info metrics, or a stale marker.
+func (p *Pipeline) Run(
+	metricResults []model.CloudwatchMetricResult,
+	taggedResources []model.TaggedResourceResult,
+	alarms []model.AlarmResult,
+	labelsSnakeCase bool,
+	logger *slog.Logger,
+) ([]*PrometheusMetric, error) {
+	ApplyRateTracking(metricResults, p.RateTracker)
+	staleMarkers := ApplyStartTimeTracking(metricResults, p.Adjuster)
+
+	metrics, observedLabels, err := BuildMetrics(metricResults, labelsSnakeCase, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, observedLabels = BuildNamespaceInfoMetrics(taggedResources, metrics, observedLabels, labelsSnakeCase, logger)
+	metrics, observedLabels = BuildAlarmStateMetrics(alarms, metrics, observedLabels, labelsSnakeCase, logger)
+	metrics = append(metrics, staleMarkers...)
+
+	metrics = EnsureLabelConsistencyAndRemoveDuplicates(metrics, observedLabels)
+	return ApplyTopK(metrics, p.TopK), nil
+}
+
+// RunEMF applies the same rate tracking Run does, then hands metricResults to BuildEMFLogs
+// instead of BuildMetrics, producing EMF log records from this data instead of Prometheus
+// metrics. It shares the RateTracker
+// with Run so a resource reported through both paths doesn't maintain two independent rate
+// histories for the same series. Start-time tracking doesn't apply to EMF records, since EMF
+// doesn't carry a Prometheus-style StartTimestamp field.
+func (p *Pipeline) RunEMF(metricResults []model.CloudwatchMetricResult) ([]byte, error) {
+	ApplyRateTracking(metricResults, p.RateTracker)
+	return BuildEMFLogs(metricResults)
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestPipeline_Run_AppliesRateTrackingBeforeBuildingMetrics(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// newScrape builds the single most-recent datapoint GetMetricData would return for this
+	// metric in one scrape - a real job never sees two datapoints for the same metric in the
+	// same CloudwatchMetricResult, so the two scrapes below are modeled as two separate calls.
+	newScrape := func(datapoint float64, timestamp time.Time) []model.CloudwatchMetricResult {
+		return []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.CloudwatchData{{
+				MetricName:            "RequestCount",
+				Namespace:             "AWS/ApplicationELB",
+				ResourceName:          "app/my-alb/50dc6c495c0c9188",
+				MetricMigrationParams: model.MetricMigrationParams{RateOverPreviousPeriod: true},
+				GetMetricDataResult:   &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(datapoint), Timestamp: timestamp},
+			}},
+		}}
+	}
+
+	pipeline := NewPipeline(time.Minute, nil, model.TopK{})
+
+	// First scrape only seeds the rate tracker.
+	metrics, err := pipeline.Run(newScrape(1000, ts), nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, 1000.0, metrics[0].Value)
+
+	// Second scrape, 60 seconds later with 300 more requests: the raw cumulative datapoint
+	// becomes a per-second rate before BuildMetrics ever sees it.
+	metrics, err = pipeline.Run(newScrape(1300, ts.Add(time.Minute)), nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.InDelta(t, 5.0, metrics[0].Value, 0.0001)
+}
+
+func TestPipeline_Run_AppendsInfoAndAlarmMetrics(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	metricResults := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{{
+			MetricName:          "RequestCount",
+			Namespace:           "AWS/ApplicationELB",
+			ResourceName:        "app/my-alb/50dc6c495c0c9188",
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(1000), Timestamp: ts},
+		}},
+	}}
+
+	taggedResources := []model.TaggedResourceResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.TaggedResource{{
+			ARN:       "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/50dc6c495c0c9188",
+			Namespace: "AWS/ApplicationELB",
+		}},
+	}}
+
+	alarms := []model.AlarmResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.Alarm{{
+			ARN:       "arn:aws:cloudwatch:us-east-1:123456789012:alarm:high-5xx",
+			AlarmName: "high-5xx",
+			Namespace: "AWS/ApplicationELB",
+			State:     "OK",
+		}},
+	}}
+
+	pipeline := NewPipeline(time.Minute, nil, model.TopK{})
+	metrics, err := pipeline.Run(metricResults, taggedResources, alarms, false, slog.Default())
+	require.NoError(t, err)
+
+	byName := map[string]int{}
+	for _, m := range metrics {
+		byName[m.Name]++
+	}
+	require.Equal(t, 1, byName["aws_applicationelb_request_count_sum"])
+	require.Equal(t, 1, byName["aws_applicationelb_info"])
+	require.Equal(t, 1, byName["aws_applicationelb_alarm_info"])
+}
+
+func TestPipeline_RunEMF_AppliesRateTrackingBeforeBuildingRecords(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newScrape := func(datapoint float64, timestamp time.Time) []model.CloudwatchMetricResult {
+		return []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.CloudwatchData{{
+				MetricName:            "RequestCount",
+				Namespace:             "AWS/ApplicationELB",
+				ResourceName:          "app/my-alb/50dc6c495c0c9188",
+				MetricMigrationParams: model.MetricMigrationParams{RateOverPreviousPeriod: true},
+				GetMetricDataResult:   &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(datapoint), Timestamp: timestamp},
+			}},
+		}}
+	}
+
+	pipeline := NewPipeline(time.Minute, nil, model.TopK{})
+
+	_, err := pipeline.RunEMF(newScrape(1000, ts))
+	require.NoError(t, err)
+
+	out, err := pipeline.RunEMF(newScrape(1300, ts.Add(time.Minute)))
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"RequestCount":5`)
+}
+
+func TestPipeline_Run_StampsCounterStartTimestampAndEmitsStaleMarker(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newScrape := func(datapoint float64, timestamp time.Time) []model.CloudwatchMetricResult {
+		return []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.CloudwatchData{{
+				MetricName:          "RequestCount",
+				Namespace:           "AWS/ApplicationELB",
+				ResourceName:        "app/my-alb/50dc6c495c0c9188",
+				GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(datapoint), Timestamp: timestamp},
+			}},
+		}}
+	}
+
+	pipeline := NewPipeline(time.Minute, []string{"AWS/ApplicationELB"}, model.TopK{})
+
+	metrics, err := pipeline.Run(newScrape(1000, ts), nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, ts, metrics[0].StartTimestamp)
+
+	// Same series, a later scrape: StartTimestamp stays pinned to the first observation.
+	metrics, err = pipeline.Run(newScrape(1300, ts.Add(time.Minute)), nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, ts, metrics[0].StartTimestamp)
+
+	// The series vanishes from CloudWatch on the next scrape - Run reports it once as stale.
+	metrics, err = pipeline.Run(nil, nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "aws_applicationelb_request_count_sum", metrics[0].Name)
+	require.True(t, math.IsNaN(metrics[0].Value))
+}
+
+func TestPipeline_Run_AppliesTopK(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newBucket := func(name string, size float64) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:          "BucketSizeBytes",
+			Namespace:           "AWS/S3",
+			ResourceName:        name,
+			Dimensions:          []model.Dimension{{Name: "BucketName", Value: name}},
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Average", Datapoint: aws.Float64(size), Timestamp: ts},
+		}
+	}
+
+	metricResults := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newBucket("bucket-a", 300),
+			newBucket("bucket-b", 100),
+			newBucket("bucket-c", 200),
+		},
+	}}
+
+	pipeline := NewPipeline(time.Minute, nil, model.TopK{Limit: 2, Order: "desc"})
+	metrics, err := pipeline.Run(metricResults, nil, nil, false, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, metrics, 3, "2 kept + 1 _other series for the metric past the limit")
+
+	byValue := map[float64]bool{}
+	for _, m := range metrics {
+		byValue[m.Value] = true
+	}
+	require.True(t, byValue[300])
+	require.True(t, byValue[200])
+	require.True(t, byValue[100], "bucket-b's value is folded into the _other series")
+}
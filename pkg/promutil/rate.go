@@ -0,0 +1,173 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// rateSample is the last datapoint observed for a given series, used by RateTracker to
+// compute a per-second delta on the next scrape.
+type rateSample struct {
+	timestamp time.Time
+	value     float64
+	lastSeen  time.Time
+}
+
+// RateTracker turns successive cumulative CloudWatch datapoints (e.g. NetworkBytesIn,
+// NumberOfMessagesSent) into a per-second rate, keyed by a fingerprint of the metric name and
+// its full label set. This mirrors monasca-transform's calculate_rate usage component: the
+// first observation for a key only primes the cache and produces no sample, steady-state
+// observations emit (current-previous)/(ts-previous ts).Seconds(), and a negative delta (a
+// counter reset) is dropped rather than reported as a bogus negative rate.
+type RateTracker struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxGap  time.Duration
+	entries map[string]rateSample
+}
+
+// NewRateTracker creates a RateTracker that evicts entries that have not been seen for ttl.
+// Callers should pick a ttl of roughly 2x the scrape interval so that resources which stop
+// reporting don't leak memory, while still surviving a couple of missed scrapes.
+//
+// maxGap bounds how large a gap between two consecutive datapoints is still trusted to produce
+// a rate: a collector outage or a long pause in reporting can make (current-previous)/(ts-prev
+// ts) technically well-defined but not meaningfully a "current" rate anymore, so a gap wider
+// than maxGap is treated the same as a counter reset - the series is reseeded rather than
+// reported. maxGap <= 0 disables the check.
+func NewRateTracker(ttl, maxGap time.Duration) *RateTracker {
+	return &RateTracker{
+		ttl:     ttl,
+		maxGap:  maxGap,
+		entries: map[string]rateSample{},
+	}
+}
+
+// Rate records the datapoint (timestamp, value) for fingerprint and returns the per-second
+// rate since the previously recorded datapoint. ok is false when there is no prior datapoint
+// to compare against (first observation for this series), when the new timestamp is not after
+// the previous one, when the gap between them exceeds maxGap, or when the new value is lower
+// than the previous one (a counter reset); in every case the series is reseeded from value.
+func (t *RateTracker) Rate(fingerprint string, timestamp time.Time, value float64) (rate float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	prev, found := t.entries[fingerprint]
+	t.entries[fingerprint] = rateSample{timestamp: timestamp, value: value, lastSeen: now}
+
+	if !found {
+		return 0, false
+	}
+
+	elapsed := timestamp.Sub(prev.timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	if t.maxGap > 0 && timestamp.Sub(prev.timestamp) > t.maxGap {
+		// The gap since the last datapoint is too large to trust as "current": treat it the
+		// same as a counter reset and reseed from this value instead of reporting a rate that
+		// silently averages over a collector outage or a long reporting pause.
+		return 0, false
+	}
+
+	delta := value - prev.value
+	if delta < 0 {
+		// Counter reset: treat the new value as the start of a fresh series rather than
+		// reporting a negative rate.
+		return 0, false
+	}
+
+	return delta / elapsed, true
+}
+
+// Evict removes any series that have not had Rate called for them in over ttl, preventing
+// resources that disappeared from CloudWatch (deleted queues, terminated instances, ...) from
+// leaking memory indefinitely.
+func (t *RateTracker) Evict() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := time.Now().Add(-t.ttl)
+	for key, sample := range t.entries {
+		if sample.lastSeen.Before(cutoff) {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// ApplyRateTracking overwrites, in place, the datapoint of every CloudwatchData entry whose
+// MetricMigrationParams.RateOverPreviousPeriod is set with the per-second rate tracker computes
+// against that series' previous scrape (see RateTracker.Rate). It must run before BuildMetrics,
+// which has no knowledge of rate tracking and simply builds a PrometheusMetric from whatever
+// datapoint it finds. A series with no prior observation, or a detected counter reset, keeps its
+// raw cumulative value for this scrape - RateTracker.Rate's "first observation primes the cache"
+// semantics.
+func ApplyRateTracking(data []model.CloudwatchMetricResult, tracker *RateTracker) {
+	for _, result := range data {
+		for _, d := range result.Data {
+			if !d.MetricMigrationParams.RateOverPreviousPeriod {
+				continue
+			}
+			if d.GetMetricDataResult == nil || d.GetMetricDataResult.Datapoint == nil {
+				continue
+			}
+
+			var accountID, region string
+			if result.Context != nil {
+				accountID = result.Context.AccountID
+				region = result.Context.Region
+			}
+			name := strings.Join([]string{accountID, region, d.Namespace, d.ResourceName, d.MetricName, d.GetMetricDataResult.Statistic}, "|")
+			fp := rateFingerprint(name, dimensionLabels(d))
+			rate, ok := tracker.Rate(fp, d.GetMetricDataResult.Timestamp, *d.GetMetricDataResult.Datapoint)
+			if ok {
+				*d.GetMetricDataResult.Datapoint = rate
+			}
+		}
+	}
+}
+
+// dimensionLabels turns d's dimensions into the map[string]string rateFingerprint expects.
+func dimensionLabels(d *model.CloudwatchData) map[string]string {
+	labels := make(map[string]string, len(d.Dimensions))
+	for _, dim := range d.Dimensions {
+		labels[dim.Name] = dim.Value
+	}
+	return labels
+}
+
+// rateFingerprint builds a stable key for a series from its metric name and label set so that
+// two datapoints for the same namespace + dimensions + resource name + account/region are
+// recognized as the same series across scrapes regardless of map iteration order.
+func rateFingerprint(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ",%s=%s", k, labels[k])
+	}
+	return b.String()
+}
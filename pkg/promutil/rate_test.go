@@ -0,0 +1,130 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestRateTracker_Rate(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := rateFingerprint("aws_elasticache_network_bytes_in_sum", map[string]string{
+		"name":                     "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+		"dimension_CacheClusterId": "redis-cluster",
+	})
+
+	tracker := NewRateTracker(time.Minute, 0)
+
+	// First observation only seeds the cache; there is nothing to compare against yet.
+	rate, ok := tracker.Rate(fingerprint, ts, 1000)
+	require.False(t, ok)
+	require.Zero(t, rate)
+
+	// Steady state: 300 bytes over 60 seconds is 5 bytes/sec.
+	rate, ok = tracker.Rate(fingerprint, ts.Add(60*time.Second), 1300)
+	require.True(t, ok)
+	require.InDelta(t, 5.0, rate, 0.0001)
+
+	// Counter reset (e.g. instance replaced): value drops below the previous observation, so
+	// the series is reseeded rather than reporting a negative rate.
+	rate, ok = tracker.Rate(fingerprint, ts.Add(120*time.Second), 50)
+	require.False(t, ok)
+	require.Zero(t, rate)
+
+	// Next scrape after the reset resumes computing a normal rate from the reseeded value.
+	rate, ok = tracker.Rate(fingerprint, ts.Add(180*time.Second), 110)
+	require.True(t, ok)
+	require.InDelta(t, 1.0, rate, 0.0001)
+}
+
+func TestRateTracker_Eviction(t *testing.T) {
+	fingerprint := rateFingerprint("aws_sqs_number_of_messages_sent_sum", map[string]string{"name": "queue-1"})
+	tracker := NewRateTracker(time.Millisecond, 0)
+
+	_, ok := tracker.Rate(fingerprint, time.Now(), 1)
+	require.False(t, ok)
+	require.Len(t, tracker.entries, 1)
+
+	time.Sleep(5 * time.Millisecond)
+	tracker.Evict()
+	require.Empty(t, tracker.entries, "stale series should be evicted once past TTL")
+}
+
+func TestRateTracker_GapTooLargeIsTreatedAsReset(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := rateFingerprint("aws_sqs_number_of_messages_sent_sum", map[string]string{"name": "queue-1"})
+
+	tracker := NewRateTracker(time.Hour, 5*time.Minute)
+
+	rate, ok := tracker.Rate(fingerprint, ts, 1000)
+	require.False(t, ok)
+	require.Zero(t, rate)
+
+	// A collector outage leaves a 30-minute gap, well past maxGap: reporting a rate here would
+	// silently average the missed period in, so the series is reseeded instead.
+	rate, ok = tracker.Rate(fingerprint, ts.Add(30*time.Minute), 5000)
+	require.False(t, ok)
+	require.Zero(t, rate)
+
+	// The next scrape, 60 seconds after the reseed, resumes computing a normal rate.
+	rate, ok = tracker.Rate(fingerprint, ts.Add(30*time.Minute+60*time.Second), 5120)
+	require.True(t, ok)
+	require.InDelta(t, 2.0, rate, 0.0001)
+}
+
+func TestRateFingerprint_StableAcrossLabelOrder(t *testing.T) {
+	a := rateFingerprint("metric", map[string]string{"a": "1", "b": "2"})
+	b := rateFingerprint("metric", map[string]string{"b": "2", "a": "1"})
+	require.Equal(t, a, b)
+}
+
+func TestApplyRateTracking(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newResult := func(datapoint float64, timestamp time.Time, rateOverPreviousPeriod bool) []model.CloudwatchMetricResult {
+		return []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.CloudwatchData{{
+				MetricName:            "NumberOfMessagesSent",
+				Namespace:             "AWS/SQS",
+				ResourceName:          "queue-1",
+				MetricMigrationParams: model.MetricMigrationParams{RateOverPreviousPeriod: rateOverPreviousPeriod},
+				GetMetricDataResult:   &model.GetMetricDataResult{Statistic: "Sum", Datapoint: aws.Float64(datapoint), Timestamp: timestamp},
+			}},
+		}}
+	}
+
+	tracker := NewRateTracker(time.Hour, 0)
+
+	// First scrape only seeds the tracker: the raw cumulative value is left untouched.
+	first := newResult(1000, ts, true)
+	ApplyRateTracking(first, tracker)
+	require.Equal(t, 1000.0, *first[0].Data[0].GetMetricDataResult.Datapoint)
+
+	// Second scrape, 60 seconds later with 300 more messages sent: the datapoint is overwritten
+	// with the 5 messages/sec rate.
+	second := newResult(1300, ts.Add(60*time.Second), true)
+	ApplyRateTracking(second, tracker)
+	require.InDelta(t, 5.0, *second[0].Data[0].GetMetricDataResult.Datapoint, 0.0001)
+
+	// A series not opted into rate tracking is left alone even though the tracker already holds
+	// a prior datapoint for the same series.
+	untracked := newResult(42, ts.Add(120*time.Second), false)
+	ApplyRateTracking(untracked, tracker)
+	require.Equal(t, 42.0, *untracked[0].Data[0].GetMetricDataResult.Datapoint)
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package starttime tracks series identity across scrapes so that counter-typed CloudWatch
+// metrics (e.g. AWS/ApplicationELB RequestCount Sum) can be exposed with a stable
+// StartTimestamp and so resources that disappear between scrapes are re-emitted once as a
+// Prometheus stale marker instead of silently vanishing, which is what lets downstream TSDBs
+// use rate() correctly across collector restarts.
+package starttime
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// StaleNaN is the same bit pattern Prometheus uses internally to mark a sample as stale: a NaN
+// with a distinguishing payload so it can never be confused with an ordinary missing value.
+var StaleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// Classifier reports whether a given namespace/statistic pair should be treated as a counter
+// (and therefore needs a StartTimestamp) as opposed to a gauge. YACE configures this per
+// namespace since, for example, `Sum` on AWS/ApplicationELB RequestCount is monotonic but `Sum`
+// on AWS/Lambda Errors within a period is not meaningfully cumulative across periods.
+type Classifier func(namespace, statistic string) bool
+
+// NewNamespaceClassifier builds a Classifier from a static set of namespaces whose `Sum`
+// statistic should be treated as a counter.
+func NewNamespaceClassifier(counterNamespaces map[string]bool) Classifier {
+	return func(namespace, statistic string) bool {
+		return statistic == "Sum" && counterNamespaces[namespace]
+	}
+}
+
+type series struct {
+	firstSeen   time.Time
+	lastSeen    time.Time
+	lastValue   float64
+	markedStale bool
+}
+
+// Adjuster maintains, per series fingerprint, the first-observed timestamp and the
+// last-observed value and timestamp across scrapes.
+type Adjuster struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	isCounter Classifier
+	series    map[string]*series
+}
+
+// New creates an Adjuster. ttl is how long a series may go unseen before Evict drops it
+// entirely, so that state for vanished resources does not accumulate; pick roughly 2x the
+// scrape interval.
+func New(ttl time.Duration, isCounter Classifier) *Adjuster {
+	return &Adjuster{
+		ttl:       ttl,
+		isCounter: isCounter,
+		series:    map[string]*series{},
+	}
+}
+
+// Observe records a datapoint for fingerprint and returns the StartTimestamp that should be
+// attached to it (the first time this series was ever observed) along with whether the series
+// is classified as a counter. For a gauge, startTimestamp is the zero time and should be
+// ignored by the caller.
+func (a *Adjuster) Observe(fingerprint, namespace, statistic string, timestamp time.Time, value float64) (startTimestamp time.Time, isCounter bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	isCounter = a.isCounter != nil && a.isCounter(namespace, statistic)
+
+	s, ok := a.series[fingerprint]
+	if !ok {
+		s = &series{firstSeen: timestamp}
+		a.series[fingerprint] = s
+	}
+	s.lastSeen = time.Now()
+	s.lastValue = value
+	s.markedStale = false
+
+	if !isCounter {
+		return time.Time{}, false
+	}
+	return s.firstSeen, true
+}
+
+// Missing returns the fingerprints that were tracked from a previous call to Observe but are
+// not present in seenThisScrape, i.e. series that disappeared from CloudWatch between scrapes
+// and should be re-emitted once as a Prometheus stale marker. A given fingerprint is only
+// returned the first scrape it's found missing; it stays silent on every following call until
+// either Observe sees it again or Evict drops it, so a vanished series produces exactly one
+// stale marker instead of one on every scrape for the rest of its ttl.
+func (a *Adjuster) Missing(seenThisScrape map[string]struct{}) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var missing []string
+	for fingerprint, s := range a.series {
+		if _, ok := seenThisScrape[fingerprint]; ok {
+			continue
+		}
+		if s.markedStale {
+			continue
+		}
+		s.markedStale = true
+		missing = append(missing, fingerprint)
+	}
+	return missing
+}
+
+// Evict drops series that have not been observed in over ttl, so that the Adjuster does not
+// keep state for resources that disappeared long ago. An evicted series that is observed again
+// starts over with a new first-seen time.
+func (a *Adjuster) Evict() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := time.Now().Add(-a.ttl)
+	for fingerprint, s := range a.series {
+		if s.lastSeen.Before(cutoff) {
+			delete(a.series, fingerprint)
+		}
+	}
+}
+
+// Reset clears all tracked series. Call this on a job config reload, since a reloaded job's
+// series identity should start fresh rather than comparing against state seen under the old
+// configuration.
+func (a *Adjuster) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.series = map[string]*series{}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package starttime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjuster_NewSeries(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	classifier := NewNamespaceClassifier(map[string]bool{"AWS/ApplicationELB": true})
+	adjuster := New(time.Hour, classifier)
+
+	start, isCounter := adjuster.Observe("fp1", "AWS/ApplicationELB", "Sum", ts, 10)
+	require.True(t, isCounter)
+	require.Equal(t, ts, start, "first observation should use its own timestamp as StartTimestamp")
+
+	start, isCounter = adjuster.Observe("fp1", "AWS/ApplicationELB", "Sum", ts.Add(time.Minute), 20)
+	require.True(t, isCounter)
+	require.Equal(t, ts, start, "subsequent observations should keep the original StartTimestamp")
+}
+
+func TestAdjuster_GaugesAreNotClassifiedAsCounters(t *testing.T) {
+	classifier := NewNamespaceClassifier(map[string]bool{"AWS/ApplicationELB": true})
+	adjuster := New(time.Hour, classifier)
+
+	start, isCounter := adjuster.Observe("fp1", "AWS/ElastiCache", "Average", time.Now(), 1)
+	require.False(t, isCounter)
+	require.True(t, start.IsZero())
+}
+
+func TestAdjuster_MissingThenReturning(t *testing.T) {
+	adjuster := New(time.Hour, NewNamespaceClassifier(nil))
+	ts := time.Now()
+	adjuster.Observe("fp1", "ns", "Sum", ts, 1)
+	adjuster.Observe("fp2", "ns", "Sum", ts, 2)
+
+	missing := adjuster.Missing(map[string]struct{}{"fp1": {}})
+	require.Equal(t, []string{"fp2"}, missing, "fp2 was tracked previously but absent from this scrape")
+
+	// fp2 reappears on the next scrape.
+	adjuster.Observe("fp2", "ns", "Sum", ts.Add(time.Minute), 3)
+	missing = adjuster.Missing(map[string]struct{}{"fp1": {}, "fp2": {}})
+	require.Empty(t, missing)
+}
+
+func TestAdjuster_MissingIsReportedOnlyOnce(t *testing.T) {
+	adjuster := New(time.Hour, NewNamespaceClassifier(nil))
+	ts := time.Now()
+	adjuster.Observe("fp1", "ns", "Sum", ts, 1)
+
+	missing := adjuster.Missing(map[string]struct{}{})
+	require.Equal(t, []string{"fp1"}, missing, "fp1 is missing on its first absent scrape")
+
+	missing = adjuster.Missing(map[string]struct{}{})
+	require.Empty(t, missing, "fp1 was already marked stale, so it must not be reported again")
+
+	missing = adjuster.Missing(map[string]struct{}{})
+	require.Empty(t, missing, "still not reported on a third consecutive absent scrape")
+
+	// fp1 reappears, then disappears again: it should be reported as missing exactly once more.
+	adjuster.Observe("fp1", "ns", "Sum", ts.Add(time.Minute), 2)
+	missing = adjuster.Missing(map[string]struct{}{})
+	require.Equal(t, []string{"fp1"}, missing, "reappearing clears the stale bit, so it's reportable again")
+
+	missing = adjuster.Missing(map[string]struct{}{})
+	require.Empty(t, missing)
+}
+
+func TestAdjuster_ColdCacheAfterRestart(t *testing.T) {
+	adjuster := New(time.Hour, NewNamespaceClassifier(nil))
+	missing := adjuster.Missing(map[string]struct{}{"fp1": {}})
+	require.Empty(t, missing, "a freshly created adjuster has nothing to compare against")
+}
+
+func TestAdjuster_TTLEviction(t *testing.T) {
+	adjuster := New(time.Millisecond, NewNamespaceClassifier(nil))
+	adjuster.Observe("fp1", "ns", "Sum", time.Now(), 1)
+	require.Len(t, adjuster.series, 1)
+
+	time.Sleep(5 * time.Millisecond)
+	adjuster.Evict()
+	require.Empty(t, adjuster.series, "series unseen for longer than the TTL should be evicted")
+}
+
+func TestAdjuster_Reset(t *testing.T) {
+	adjuster := New(time.Hour, NewNamespaceClassifier(nil))
+	adjuster.Observe("fp1", "ns", "Sum", time.Now(), 1)
+	require.Len(t, adjuster.series, 1)
+
+	adjuster.Reset()
+	require.Empty(t, adjuster.series)
+}
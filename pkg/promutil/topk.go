@@ -0,0 +1,126 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import "sort"
+
+// TopKConfig configures the optional top-K post-processing stage applied after
+// EnsureLabelConsistencyAndRemoveDuplicates. It is meant for namespaces whose resource count
+// can explode the series cardinality of a single metric (S3 buckets, Lambda functions, ...),
+// where emitting everything is wasteful but emitting nothing loses visibility entirely.
+type TopKConfig struct {
+	// Limit is the number of series to keep per metric name, ordered by Order. A Limit <= 0
+	// disables top-K for the metrics it's applied to.
+	Limit int
+	// Order is "asc" or "desc" (default "desc" - keep the highest values).
+	Order string
+	// GroupBy lists the label keys that get collapsed to "_other" on the synthetic series
+	// that summarizes everything past Limit. If empty, the "name" label is collapsed.
+	GroupBy []string
+	// OtherAggregation is how the dropped series' values are combined into the "_other"
+	// series: "sum" (default), "avg", or "max".
+	OtherAggregation string
+}
+
+// ApplyTopK keeps, for each distinct metric Name, only the top cfg.Limit series ordered by
+// Value, and folds the rest into a single "_other" series per metric name using
+// cfg.OtherAggregation. Metric names with no more series than the limit are left untouched.
+func ApplyTopK(metrics []*PrometheusMetric, cfg TopKConfig) []*PrometheusMetric {
+	if cfg.Limit <= 0 {
+		return metrics
+	}
+
+	var order []string
+	byName := map[string][]*PrometheusMetric{}
+	for _, m := range metrics {
+		if _, ok := byName[m.Name]; !ok {
+			order = append(order, m.Name)
+		}
+		byName[m.Name] = append(byName[m.Name], m)
+	}
+
+	out := make([]*PrometheusMetric, 0, len(metrics))
+	for _, name := range order {
+		group := byName[name]
+		if len(group) <= cfg.Limit {
+			out = append(out, group...)
+			continue
+		}
+
+		sort.Slice(group, func(i, j int) bool {
+			if cfg.Order == "asc" {
+				return group[i].Value < group[j].Value
+			}
+			return group[i].Value > group[j].Value
+		})
+
+		out = append(out, group[:cfg.Limit]...)
+		out = append(out, otherSeries(name, group[cfg.Limit:], cfg))
+	}
+
+	return out
+}
+
+// otherSeries folds dropped into a single series representing everything excluded by the
+// top-K cut for a given metric name.
+func otherSeries(name string, dropped []*PrometheusMetric, cfg TopKConfig) *PrometheusMetric {
+	labels := map[string]string{}
+	if len(dropped) > 0 {
+		for k, v := range dropped[0].Labels {
+			labels[k] = v
+		}
+	}
+
+	groupBy := cfg.GroupBy
+	if len(groupBy) == 0 {
+		groupBy = []string{"name"}
+	}
+	for _, key := range groupBy {
+		labels[key] = "_other"
+	}
+
+	return &PrometheusMetric{
+		Name:   name,
+		Labels: labels,
+		Value:  aggregateOther(dropped, cfg.OtherAggregation),
+	}
+}
+
+func aggregateOther(dropped []*PrometheusMetric, aggregation string) float64 {
+	if len(dropped) == 0 {
+		return 0
+	}
+
+	switch aggregation {
+	case "avg":
+		var sum float64
+		for _, m := range dropped {
+			sum += m.Value
+		}
+		return sum / float64(len(dropped))
+	case "max":
+		max := dropped[0].Value
+		for _, m := range dropped[1:] {
+			if m.Value > max {
+				max = m.Value
+			}
+		}
+		return max
+	default: // "sum"
+		var sum float64
+		for _, m := range dropped {
+			sum += m.Value
+		}
+		return sum
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func syntheticMetrics(n int) []*PrometheusMetric {
+	metrics := make([]*PrometheusMetric, 0, n)
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, &PrometheusMetric{
+			Name:   "aws_s3_bucket_size_bytes_average",
+			Labels: map[string]string{"dimension_BucketName": fmt.Sprintf("bucket-%d", i)},
+			Value:  float64(i),
+		})
+	}
+	return metrics
+}
+
+func TestApplyTopK(t *testing.T) {
+	metrics := syntheticMetrics(10)
+
+	out := ApplyTopK(metrics, TopKConfig{Limit: 3, Order: "desc", OtherAggregation: "sum"})
+	require.Len(t, out, 4) // top 3 + 1 "_other" bucket
+
+	require.Equal(t, 9.0, out[0].Value)
+	require.Equal(t, 8.0, out[1].Value)
+	require.Equal(t, 7.0, out[2].Value)
+
+	other := out[3]
+	require.Equal(t, "_other", other.Labels["name"])
+	// sum(0..6) = 21
+	require.Equal(t, 21.0, other.Value)
+}
+
+func TestApplyTopK_BelowLimitIsUntouched(t *testing.T) {
+	metrics := syntheticMetrics(2)
+	out := ApplyTopK(metrics, TopKConfig{Limit: 10})
+	require.Equal(t, metrics, out)
+}
+
+func TestApplyTopK_Disabled(t *testing.T) {
+	metrics := syntheticMetrics(5)
+	out := ApplyTopK(metrics, TopKConfig{Limit: 0})
+	require.Equal(t, metrics, out)
+}
+
+func TestApplyTopK_OtherAggregationModes(t *testing.T) {
+	metrics := syntheticMetrics(5) // values 0..4
+
+	avgOut := ApplyTopK(metrics, TopKConfig{Limit: 1, Order: "desc", OtherAggregation: "avg"})
+	require.InDelta(t, 1.5, avgOut[1].Value, 0.0001) // avg(0,1,2,3) = 1.5
+
+	maxOut := ApplyTopK(metrics, TopKConfig{Limit: 1, Order: "desc", OtherAggregation: "max"})
+	require.Equal(t, 3.0, maxOut[1].Value)
+}
+
+func BenchmarkApplyTopK_10kResources(b *testing.B) {
+	metrics := syntheticMetrics(10000)
+	cfg := TopKConfig{Limit: 50, Order: "desc", OtherAggregation: "sum"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var out []*PrometheusMetric
+	for i := 0; i < b.N; i++ {
+		out = ApplyTopK(metrics, cfg)
+	}
+	b.StopTimer()
+
+	// 50 kept + 1 "_other" bucket, down from 10k - demonstrates the series reduction at the
+	// output of BuildMetrics that topK is meant to provide for huge accounts.
+	if len(out) != 51 {
+		b.Fatalf("expected top-K to reduce 10000 series to 51, got %d", len(out))
+	}
+}
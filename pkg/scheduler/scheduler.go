@@ -0,0 +1,104 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler implements the background collection loop that
+// config.DecoupledScraping describes: a goroutine that runs a collection on its own cadence
+// and caches the result, so that a `/metrics` handler can serve the cache instead of blocking
+// on a live CloudWatch collection.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// CollectFunc runs one full collection pass and returns its result (e.g. rendered Prometheus
+// text or a []*promutil.PrometheusMetric) or an error.
+type CollectFunc func(ctx context.Context) ([]byte, error)
+
+// Cache runs collect on a fixed interval, optionally staggered by a random jitter, and serves
+// the most recent successful result. A collection that errors leaves the previous result in
+// place rather than clearing the cache, so a single failed scrape of CloudWatch doesn't cause
+// `/metrics` to go empty.
+type Cache struct {
+	interval time.Duration
+	jitter   time.Duration
+	collect  CollectFunc
+	logger   *slog.Logger
+
+	mu   sync.RWMutex
+	last []byte
+}
+
+// NewCache creates a Cache. interval is the fixed collection cadence (config.DecoupledScraping
+// .ScrapeInterval); jitter, if non-zero, delays each scheduled run after a tick by a random
+// amount in [0, jitter) so that many jobs configured with the same interval don't all hit the
+// CloudWatch API at the same instant. The first run, made as soon as Run starts, is not delayed.
+func NewCache(interval, jitter time.Duration, collect CollectFunc, logger *slog.Logger) *Cache {
+	return &Cache{
+		interval: interval,
+		jitter:   jitter,
+		collect:  collect,
+		logger:   logger,
+	}
+}
+
+// Run blocks, collecting on c.interval until ctx is done. Call it in its own goroutine.
+func (c *Cache) Run(ctx context.Context) {
+	c.runOnce(ctx)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.jitter > 0 {
+				select {
+				case <-time.After(time.Duration(rand.Int63n(int64(c.jitter)))):
+				case <-ctx.Done():
+					return
+				}
+			}
+			c.runOnce(ctx)
+		}
+	}
+}
+
+func (c *Cache) runOnce(ctx context.Context) {
+	result, err := c.collect(ctx)
+	if err != nil {
+		c.logger.Error("decoupled scrape failed, serving the previous result", "err", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.last = result
+	c.mu.Unlock()
+}
+
+// Get returns the most recently cached collection result. It returns (nil, false) until the
+// first collection completes successfully.
+func (c *Cache) Get() ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.last == nil {
+		return nil, false
+	}
+	return c.last, true
+}
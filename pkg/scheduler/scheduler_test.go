@@ -0,0 +1,70 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_GetBeforeFirstCollection(t *testing.T) {
+	cache := NewCache(time.Hour, 0, func(context.Context) ([]byte, error) {
+		return []byte("ok"), nil
+	}, promslog.NewNopLogger())
+
+	_, ok := cache.Get()
+	require.False(t, ok, "nothing has been collected yet")
+}
+
+func TestCache_RunCollectsImmediatelyThenOnInterval(t *testing.T) {
+	var calls int64
+	cache := NewCache(10*time.Millisecond, 0, func(context.Context) ([]byte, error) {
+		n := atomic.AddInt64(&calls, 1)
+		return []byte{byte(n)}, nil
+	}, promslog.NewNopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	cache.Run(ctx)
+
+	require.GreaterOrEqual(t, atomic.LoadInt64(&calls), int64(3), "expected an immediate run plus at least two ticks")
+
+	result, ok := cache.Get()
+	require.True(t, ok)
+	require.Equal(t, byte(atomic.LoadInt64(&calls)), result[0])
+}
+
+func TestCache_FailedCollectionKeepsPreviousResult(t *testing.T) {
+	var calls int64
+	cache := NewCache(5*time.Millisecond, 0, func(context.Context) ([]byte, error) {
+		n := atomic.AddInt64(&calls, 1)
+		if n == 1 {
+			return []byte("first"), nil
+		}
+		return nil, errors.New("cloudwatch unavailable")
+	}, promslog.NewNopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+	cache.Run(ctx)
+
+	result, ok := cache.Get()
+	require.True(t, ok)
+	require.Equal(t, []byte("first"), result, "a failed collection must not clear the cache")
+}